@@ -57,7 +57,9 @@ func adminUsersCmd(cmd *cli.Cmd) {
 			input, e := getInputReader(*filePathArg)
 			fatalIf(e)
 
-			u := conch.ReadUser(input)
+			u, e := conch.ReadUser(input)
+			fatalIf(e)
+
 			display(
 				conch.CreateUser(types.NewUser{
 					Email:   u.Email,
@@ -89,7 +91,7 @@ func adminUserCmd(cmd *cli.Cmd) {
 
 	cmd.Action = func() { display(user, nil) }
 
-	cmd.Command("get", "display all users", func(cmd *cli.Cmd) {
+	cmd.Command("get", "display the user's details", func(cmd *cli.Cmd) {
 		cmd.Action = func() { display(user, nil) }
 	})
 
@@ -111,13 +113,13 @@ func adminUserCmd(cmd *cli.Cmd) {
 			if *admin != user.IsAdmin {
 				user.IsAdmin = *admin
 			}
-			conch.UpdateUser(string(user.Email), update, *notify)
+			fatalIf(conch.UpdateUser(string(user.Email), update, *notify))
 		}
 	})
 
 	cmd.Command("delete rm", "remove the specified user", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
-			conch.DeleteUser(string(user.Email))
+			fatalIf(conch.DeleteUser(string(user.Email)))
 			display(conch.GetAllUsers())
 		}
 	})
@@ -149,7 +151,7 @@ func adminUserCmd(cmd *cli.Cmd) {
 
 		cmd.Command("delete rm", "remove a token for the given user", func(cmd *cli.Cmd) {
 			cmd.Action = func() {
-				conch.DeleteUserToken(string(user.Email), token.Name)
+				fatalIf(conch.DeleteUserToken(string(user.Email), token.Name))
 				display(conch.GetUserTokens(string(user.Email)))
 			}
 		})
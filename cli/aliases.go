@@ -0,0 +1,345 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	cli "github.com/jawher/mow.cli"
+)
+
+// globalValueOptions are the global options that take a separate value
+// argument. They're needed to find the command word in an argument list
+// before mow.cli has parsed it.
+var globalValueOptions = map[string]bool{
+	"-t": true, "--token": true,
+	"-e": true, "--env": true,
+	"-u": true, "--url": true,
+	"--columns":     true,
+	"--sort-by":     true,
+	"--watch":       true,
+	"--color":       true,
+	"--tz":          true,
+	"--time-format": true,
+	"--log-file":    true,
+	"--log-format":  true,
+}
+
+// commandIndex returns the index in args of the top level command word,
+// skipping over the global options and their values. It returns -1 if there
+// is no command.
+func commandIndex(args []string) int {
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			if i+1 < len(args) {
+				return i + 1
+			}
+			return -1
+		case strings.HasPrefix(a, "--"):
+			if !strings.Contains(a, "=") && globalValueOptions[a] {
+				i++
+			}
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			// a cluster of short options, only the last may take a value
+			if !strings.Contains(a, "=") && globalValueOptions["-"+a[len(a)-1:]] {
+				i++
+			}
+		default:
+			return i
+		}
+	}
+	return -1
+}
+
+// aliasParam matches the positional parameters in an alias expansion
+var aliasParam = regexp.MustCompile(`\$(@|\d+)`)
+
+// expandAlias substitutes the positional parameters $1..$N and $@ in the
+// expansion with the given arguments. Arguments that aren't referenced are
+// appended to the end.
+func expandAlias(expansion string, args []string) ([]string, error) {
+	words, e := splitWords(expansion)
+	if e != nil {
+		return nil, e
+	}
+
+	used := make([]bool, len(args))
+	expanded := []string{}
+	for _, w := range words {
+		if w == "$@" {
+			expanded = append(expanded, args...)
+			for i := range used {
+				used[i] = true
+			}
+			continue
+		}
+
+		var missing error
+		w = aliasParam.ReplaceAllStringFunc(w, func(m string) string {
+			if m == "$@" {
+				for i := range used {
+					used[i] = true
+				}
+				return strings.Join(args, " ")
+			}
+			n, _ := strconv.Atoi(m[1:])
+			if n < 1 || n > len(args) {
+				missing = usageError("alias expects at least %d arguments", n)
+				return m
+			}
+			used[n-1] = true
+			return args[n-1]
+		})
+		if missing != nil {
+			return nil, missing
+		}
+		expanded = append(expanded, w)
+	}
+
+	for i, a := range args {
+		if !used[i] {
+			expanded = append(expanded, a)
+		}
+	}
+	return expanded, nil
+}
+
+// expandAliases rewrites args if the command word is a user defined alias.
+// Built in commands and plugins, given in reserved, always win over aliases. If the alias is a shell alias
+// (its expansion starts with '!') it's run here and the returned bool is true.
+func expandAliases(args []string, aliases map[string]string, reserved map[string]bool) ([]string, bool, error) {
+	i := commandIndex(args)
+	if i < 0 {
+		return args, false, nil
+	}
+
+	name := args[i]
+	expansion, ok := aliases[name]
+	if !ok || reserved[name] {
+		return args, false, nil
+	}
+
+	if strings.HasPrefix(expansion, "!") {
+		return args, true, runShellAlias(name, strings.TrimPrefix(expansion, "!"), args[i+1:])
+	}
+
+	expanded, e := expandAlias(expansion, args[i+1:])
+	if e != nil {
+		return args, false, e
+	}
+
+	result := append([]string{}, args[:i]...)
+	return append(result, expanded...), false, nil
+}
+
+// applyAliases expands any alias in args using the aliases in the kosh
+// config file. It returns nil args if the alias was a shell alias and has
+// already been run.
+func applyAliases(args []string) ([]string, error) {
+	cf, e := loadConfigFile(configFilePath())
+	if e != nil {
+		return args, e
+	}
+	if len(cf.Aliases) == 0 {
+		return args, nil
+	}
+
+	reserved := map[string]bool{}
+	for name := range builtinCommands {
+		reserved[name] = true
+	}
+	for name := range pluginCommands {
+		reserved[name] = true
+	}
+	expanded, ran, e := expandAliases(args, cf.Aliases, reserved)
+	if ran || e != nil {
+		return nil, e
+	}
+	return expanded, nil
+}
+
+// runShellAlias runs the expansion with sh, which handles the positional
+// parameters itself
+func runShellAlias(name, script string, args []string) error {
+	run := exec.Command("sh", append([]string{"-c", script, name}, args...)...)
+	run.Stdin = os.Stdin
+	run.Stdout = os.Stdout
+	run.Stderr = os.Stderr
+
+	self, _ := os.Executable()
+	run.Env = append(os.Environ(), "KOSH_BIN="+self)
+
+	if e := run.Run(); e != nil {
+		var exit *exec.ExitError
+		if errors.As(e, &exit) {
+			return exitError{exit.ExitCode(), e}
+		}
+		return e
+	}
+	return nil
+}
+
+// splitWords splits a string into words, honouring single and double quotes
+// and backslash escapes, in the manner of a (much simplified) shell
+func splitWords(s string) ([]string, error) {
+	words := []string{}
+	word := &strings.Builder{}
+	inWord := false
+	var quote rune
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == '\'':
+			word.WriteRune(r)
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case quote == '"':
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, validationError("unterminated quote in '%s'", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// quoteWords joins words back into a single string, quoting any that
+// splitWords would otherwise break apart
+func quoteWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		if w == "" || strings.ContainsAny(w, " \t\n'\"\\") {
+			w = "'" + strings.Replace(w, "'", `'\''`, -1) + "'"
+		}
+		quoted[i] = w
+	}
+	return strings.Join(quoted, " ")
+}
+
+type aliasList [][2]string
+
+func (al aliasList) Len() int           { return len(al) }
+func (al aliasList) Swap(i, j int)      { al[i], al[j] = al[j], al[i] }
+func (al aliasList) Less(i, j int) bool { return al[i][0] < al[j][0] }
+
+// Headers returns the list of headers for the table view
+func (al aliasList) Headers() []string {
+	return []string{
+		"Name",
+		"Expansion",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (al aliasList) ForEach(do func([]string)) {
+	for _, a := range al {
+		do([]string{a[0], a[1]})
+	}
+}
+
+func aliasCmd(builtins map[string]bool) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		var cf ConfigFile
+		var path string
+
+		cmd.Before = func() {
+			path = configFilePath()
+
+			var e error
+			cf, e = loadConfigFile(path)
+			fatalIf(e)
+
+			if cf.Aliases == nil {
+				cf.Aliases = map[string]string{}
+			}
+		}
+
+		list := func() {
+			al := aliasList{}
+			for name, expansion := range cf.Aliases {
+				al = append(al, [2]string{name, expansion})
+			}
+			sort.Sort(al)
+			config.Renderer()(al, nil)
+		}
+
+		cmd.Action = list
+
+		cmd.Command("list ls", "List the defined aliases", func(cmd *cli.Cmd) {
+			cmd.Action = list
+		})
+
+		cmd.Command("set", "Create or replace an alias. $1..$N and $@ are replaced with the alias arguments. An expansion starting with '!' is run by sh", func(cmd *cli.Cmd) {
+			nameArg := cmd.StringArg("NAME", "", "Name of the alias")
+			expansionArg := cmd.StringsArg("EXPANSION", nil, "The command line the alias expands to")
+			cmd.Spec = "NAME -- EXPANSION..."
+
+			cmd.Action = func() {
+				if builtins[*nameArg] {
+					fatalIf(validationError("'%s' is a built in command and can't be aliased", *nameArg))
+				}
+				if path := pluginCommands[*nameArg]; path != "" {
+					fatalIf(validationError("'%s' is a plugin, %s, and can't be aliased", *nameArg, path))
+				}
+				if strings.HasPrefix(*nameArg, "-") || strings.ContainsAny(*nameArg, " \t") {
+					fatalIf(validationError("'%s' is not a valid alias name", *nameArg))
+				}
+
+				expansion := quoteWords(*expansionArg)
+				if len(*expansionArg) == 1 {
+					// a single argument is taken as the complete, already
+					// quoted, expansion
+					expansion = (*expansionArg)[0]
+				}
+				if _, e := splitWords(strings.TrimPrefix(expansion, "!")); e != nil {
+					fatalIf(e)
+				}
+
+				cf.Aliases[*nameArg] = expansion
+				fatalIf(cf.save(path))
+				fmt.Printf("%s: %s\n", *nameArg, expansion)
+			}
+		})
+
+		cmd.Command("delete rm", "Remove an alias", func(cmd *cli.Cmd) {
+			nameArg := cmd.StringArg("NAME", "", "Name of the alias")
+			cmd.Spec = "NAME"
+
+			cmd.Action = func() {
+				if _, ok := cf.Aliases[*nameArg]; !ok {
+					fatalIf(notFoundError("no alias named '%s'", *nameArg))
+				}
+				delete(cf.Aliases, *nameArg)
+				fatalIf(cf.save(path))
+			}
+		})
+	}
+}
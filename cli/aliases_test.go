@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandIndex(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{"kosh"}, -1},
+		{[]string{"kosh", "whoami"}, 1},
+		{[]string{"kosh", "-t", "token", "whoami"}, 3},
+		{[]string{"kosh", "--token=token", "whoami"}, 2},
+		{[]string{"kosh", "-jt", "token", "whoami"}, 3},
+		{[]string{"kosh", "-v", "--columns", "ID", "devices"}, 4},
+		{[]string{"kosh", "--", "whoami"}, 2},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, commandIndex(test.args), test.args)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	words, e := splitWords(`device $1 tag set 'my tag' "a b" c\ d`)
+	assert.Nil(t, e)
+	assert.Equal(t, []string{"device", "$1", "tag", "set", "my tag", "a b", "c d"}, words)
+
+	_, e = splitWords(`unterminated 'quote`)
+	assert.NotNil(t, e)
+
+	words, e = splitWords(quoteWords([]string{"a b", "it's", "", "c"}))
+	assert.Nil(t, e)
+	assert.Equal(t, []string{"a b", "it's", "", "c"}, words)
+}
+
+func TestExpandAliases(t *testing.T) {
+	aliases := map[string]string{
+		"dt":      "device $1 tag",
+		"mine":    "devices search --hostname=$1",
+		"every":   "racks $@ --wide",
+		"whoami":  "user profile",
+		"missing": "device $2",
+	}
+	builtins := map[string]bool{"whoami": true, "device": true}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"kosh", "-j", "dt", "abc", "set", "x"}, []string{"kosh", "-j", "device", "abc", "tag", "set", "x"}},
+		{[]string{"kosh", "mine", "host1"}, []string{"kosh", "devices", "search", "--hostname=host1"}},
+		{[]string{"kosh", "every", "a", "b"}, []string{"kosh", "racks", "a", "b", "--wide"}},
+		{[]string{"kosh", "whoami"}, []string{"kosh", "whoami"}},
+		{[]string{"kosh", "device", "abc"}, []string{"kosh", "device", "abc"}},
+	}
+	for _, test := range tests {
+		args, ran, e := expandAliases(test.args, aliases, builtins)
+		assert.Nil(t, e)
+		assert.False(t, ran)
+		assert.Equal(t, test.expected, args)
+	}
+
+	_, _, e := expandAliases([]string{"kosh", "missing", "a"}, aliases, builtins)
+	assert.Equal(t, ExitUsage, exitCode(e))
+}
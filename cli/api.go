@@ -0,0 +1,249 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+)
+
+// parseField parses a --field of the form key=value. Like gh api -F, the
+// values true, false and null and integers become the matching JSON types,
+// and a value beginning with @ is replaced with the contents of the named
+// file, or STDIN for @-.
+func parseField(field string) (string, interface{}, error) {
+	bits := strings.SplitN(field, "=", 2)
+	if len(bits) != 2 || bits[0] == "" {
+		return "", nil, usageError("--field must be of the form key=value: got '%s'", field)
+	}
+	key, value := bits[0], bits[1]
+
+	switch value {
+	case "true":
+		return key, true, nil
+	case "false":
+		return key, false, nil
+	case "null":
+		return key, nil, nil
+	}
+	if n, e := strconv.ParseInt(value, 10, 64); e == nil {
+		return key, n, nil
+	}
+	if strings.HasPrefix(value, "@") {
+		r, e := getInputReader(strings.TrimPrefix(value, "@"))
+		if e != nil {
+			return "", nil, e
+		}
+		b, e := ioutil.ReadAll(r)
+		if e != nil {
+			return "", nil, e
+		}
+		return key, string(b), nil
+	}
+	return key, value, nil
+}
+
+// parseHeader parses a --header of the form "Name: value"
+func parseHeader(header string) (string, string, error) {
+	bits := strings.SplitN(header, ":", 2)
+	if len(bits) != 2 || strings.TrimSpace(bits[0]) == "" {
+		return "", "", usageError("--header must be of the form 'Name: value': got '%s'", header)
+	}
+	return strings.TrimSpace(bits[0]), strings.TrimSpace(bits[1]), nil
+}
+
+// apiRequest is a request built from the arguments to kosh api
+type apiRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// newAPIRequest builds the request for kosh api. Fields become the JSON body
+// of the request, unless the method is GET or HEAD or the body is given with
+// --input, in which case they're added to the query string.
+func newAPIRequest(method, path string, fields, headers []string, input string) (apiRequest, error) {
+	req := apiRequest{
+		Method: strings.ToUpper(method),
+		Header: http.Header{},
+	}
+
+	for _, h := range headers {
+		name, value, e := parseHeader(h)
+		if e != nil {
+			return req, e
+		}
+		req.Header.Add(name, value)
+	}
+
+	values := map[string]interface{}{}
+	keys := []string{}
+	for _, f := range fields {
+		k, v, e := parseField(f)
+		if e != nil {
+			return req, e
+		}
+		if _, ok := values[k]; !ok {
+			keys = append(keys, k)
+		}
+		values[k] = v
+	}
+
+	inQuery := req.Method == http.MethodGet || req.Method == http.MethodHead || input != ""
+	if len(values) > 0 && inQuery {
+		u, e := url.Parse(path)
+		if e != nil {
+			return req, usageError("invalid path '%s': %s", path, e)
+		}
+		q := u.Query()
+		for _, k := range keys {
+			v := values[k]
+			if v == nil {
+				v = ""
+			}
+			q.Add(k, fmt.Sprint(v))
+		}
+		u.RawQuery = q.Encode()
+		path = u.String()
+	}
+	req.Path = path
+
+	switch {
+	case input != "":
+		r, e := getInputReader(input)
+		if e != nil {
+			return req, e
+		}
+		req.Body, e = ioutil.ReadAll(r)
+		if e != nil {
+			return req, e
+		}
+	case len(values) > 0 && !inQuery:
+		b, e := json.Marshal(values)
+		if e != nil {
+			return req, e
+		}
+		req.Body = b
+	}
+	if req.Body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// send makes the request with the given client, to the request's path or,
+// for later pages, to the given URL
+func (r apiRequest) send(client *conch.Client, path string) (*http.Response, []byte, error) {
+	var body io.Reader
+	if r.Body != nil {
+		body = bytes.NewReader(r.Body)
+	}
+	return client.Raw(r.Method, path, r.Header, body)
+}
+
+// formatJSON indents a JSON body, or compacts it for --json. Anything that
+// isn't JSON is returned as it is.
+func formatJSON(b []byte, compact bool) []byte {
+	buf := &bytes.Buffer{}
+	var e error
+	if compact {
+		e = json.Compact(buf, b)
+	} else {
+		e = json.Indent(buf, b, "", "  ")
+	}
+	if e != nil {
+		return b
+	}
+	return buf.Bytes()
+}
+
+// mergePages joins the pages of a paginated response. If every page is a
+// JSON array they are joined into a single array, otherwise the pages are
+// returned one after another.
+func mergePages(pages [][]byte) []byte {
+	merged := []json.RawMessage{}
+	for _, p := range pages {
+		var items []json.RawMessage
+		if e := json.Unmarshal(p, &items); e != nil {
+			return bytes.Join(pages, []byte("\n"))
+		}
+		merged = append(merged, items...)
+	}
+	b, e := json.Marshal(merged)
+	if e != nil {
+		return bytes.Join(pages, []byte("\n"))
+	}
+	return b
+}
+
+func apiCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Make an authenticated request to any Conch API endpoint and print the response.
+
+PATH is relative to the API URL, e.g. /build/my-build/device/pxe. JSON
+responses are pretty printed. --verbose shows the response status.
+
+Each --field key=value is sent as a member of a JSON object body, or as a
+query parameter for GET and HEAD requests or when --input is given. The values
+true, false, null and integers are sent as JSON types, and @FILE reads the
+value from FILE (@- for STDIN).`
+
+	var (
+		method   = cmd.StringArg("METHOD", "", "The HTTP method: GET, HEAD, POST, PUT, PATCH or DELETE")
+		path     = cmd.StringArg("PATH", "", "The API path to request, optionally with a query string")
+		fields   = cmd.StringsOpt("f field", nil, "Add a key=value field to the request body or query string")
+		input    = cmd.StringOpt("input", "", "Send the contents of this file as the request body (--input=- for STDIN)")
+		headers  = cmd.StringsOpt("H header", nil, "Add a 'Name: value' HTTP request header")
+		paginate = cmd.BoolOpt("paginate", false, "Follow the Link: rel=\"next\" header to fetch every page, joining JSON arrays")
+	)
+	cmd.Spec = "[OPTIONS] METHOD PATH [OPTIONS]"
+
+	cmd.Action = func() {
+		req, e := newAPIRequest(*method, *path, *fields, *headers, *input)
+		fatalIf(e)
+		if *paginate && req.Method != http.MethodGet {
+			fatalIf(usageError("--paginate can only be used with GET requests"))
+		}
+
+		client := config.ConchClient()
+		pages := [][]byte{}
+		next := req.Path
+		for next != "" {
+			res, body, e := req.send(client, next)
+			if res != nil {
+				config.Info(res.Proto, res.Status)
+			}
+
+			if e != nil {
+				if len(body) > 0 {
+					fmt.Println(string(formatJSON(body, config.OutputJSON)))
+				}
+				fatalIf(e)
+			}
+
+			pages = append(pages, body)
+			next = ""
+			if *paginate {
+				next = conch.NextPage(res)
+			}
+		}
+
+		out := pages[0]
+		if len(pages) > 1 {
+			out = mergePages(pages)
+		}
+		if len(bytes.TrimSpace(out)) == 0 {
+			return
+		}
+		fmt.Fprintln(os.Stdout, string(formatJSON(out, config.OutputJSON)))
+	}
+}
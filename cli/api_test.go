@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseField(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "value")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("from a file"), 0644))
+
+	tests := map[string]interface{}{
+		"k=v":        "v",
+		"k=42":       int64(42),
+		"k=true":     true,
+		"k=false":    false,
+		"k=null":     nil,
+		"k=a=b":      "a=b",
+		"k=@" + file: "from a file",
+	}
+	for field, want := range tests {
+		k, v, e := parseField(field)
+		assert.Nil(t, e, field)
+		assert.Equal(t, "k", k, field)
+		assert.Equal(t, want, v, field)
+	}
+
+	_, _, e := parseField("novalue")
+	assert.Equal(t, ExitUsage, exitCode(e))
+}
+
+func TestNewAPIRequest(t *testing.T) {
+	req, e := newAPIRequest("get", "/device?x=1", []string{"b=2", "a=z"}, []string{"X-Test: yes"}, "")
+	assert.Nil(t, e)
+	assert.Equal(t, "GET", req.Method)
+	assert.Equal(t, "/device?a=z&b=2&x=1", req.Path)
+	assert.Nil(t, req.Body)
+	assert.Equal(t, "yes", req.Header.Get("X-Test"))
+
+	req, e = newAPIRequest("POST", "/device/x/links", []string{"links=1", "ok=true"}, nil, "")
+	assert.Nil(t, e)
+	assert.Equal(t, "/device/x/links", req.Path)
+	assert.JSONEq(t, `{"links":1,"ok":true}`, string(req.Body))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+
+	input := filepath.Join(t.TempDir(), "body.json")
+	assert.Nil(t, ioutil.WriteFile(input, []byte(`["a"]`), 0644))
+	req, e = newAPIRequest("POST", "/thing", []string{"q=1"}, []string{"Content-Type: text/plain"}, input)
+	assert.Nil(t, e)
+	assert.Equal(t, "/thing?q=1", req.Path)
+	assert.Equal(t, `["a"]`, string(req.Body))
+	assert.Equal(t, "text/plain", req.Header.Get("Content-Type"))
+
+	_, e = newAPIRequest("GET", "/", nil, []string{"no colon"}, "")
+	assert.Equal(t, ExitUsage, exitCode(e))
+
+	_, e = newAPIRequest("POST", "/", nil, nil, filepath.Join(t.TempDir(), "missing"))
+	assert.True(t, os.IsNotExist(e))
+}
+
+func TestMergePages(t *testing.T) {
+	merged := mergePages([][]byte{[]byte(`[1,2]`), []byte(`[3]`)})
+	var items []int
+	assert.Nil(t, json.Unmarshal(merged, &items))
+	assert.Equal(t, []int{1, 2, 3}, items)
+
+	assert.Equal(t, "{}\n{}", string(mergePages([][]byte{[]byte(`{}`), []byte(`{}`)})))
+}
+
+func TestFormatJSON(t *testing.T) {
+	assert.Equal(t, "{\n  \"a\": 1\n}", string(formatJSON([]byte(`{"a":1}`), false)))
+	assert.Equal(t, `{"a":1}`, string(formatJSON([]byte("{ \"a\" : 1 }"), true)))
+	assert.Equal(t, "not json", string(formatJSON([]byte("not json"), false)))
+}
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"fmt"
 	"strings"
 	"time"
 
@@ -90,13 +89,13 @@ func buildsCmd(cmd *cli.Cmd) {
 
 		cmd.Spec = "NAME [OPTIONS]"
 		cmd.Action = func() {
-			conch.CreateBuild(
+			fatalIf(conch.CreateBuild(
 				types.BuildCreate{
 					Name:        types.MojoStandardPlaceholder(*nameArg),
 					Description: types.NonEmptyString(*descOpt),
 					Admins:      []types.Admin{types.Admin{Email: types.EmailAddress(*adminEmailArg)}},
 				},
-			)
+			))
 			getAllBuilds()
 		}
 	})
@@ -152,13 +151,13 @@ func buildCmd(cmd *cli.Cmd) {
 		cmd.Action = func() {
 			display(conch.GetBuildUsers(*buildNameArg))
 		}
-		cmd.Command("get ls", "Get a list of users in an build", func(cmd *cli.Cmd) {
+		cmd.Command("get ls", "Get a list of users in a build", func(cmd *cli.Cmd) {
 			cmd.Action = func() {
 				display(conch.GetBuildUsers(*buildNameArg))
 			}
 		})
 
-		cmd.Command("add", "Add a user to an build", func(cmd *cli.Cmd) {
+		cmd.Command("add", "Add a user to a build", func(cmd *cli.Cmd) {
 			userEmailArg := cmd.StringArg(
 				"EMAIL",
 				"",
@@ -180,24 +179,24 @@ func buildCmd(cmd *cli.Cmd) {
 			cmd.Spec = "EMAIL [OPTIONS]"
 			cmd.Action = func() {
 				if !okBuildRole(*roleOpt) {
-					fatalIf(fmt.Errorf(
+					fatalIf(validationError(
 						"'role' value must be one of: %s",
 						prettyBuildRoleList(),
 					))
 				}
-				conch.AddBuildUser(
+				fatalIf(conch.AddBuildUser(
 					*buildNameArg,
 					types.BuildAddUser{
 						Email: types.EmailAddress(*userEmailArg),
 						Role:  types.Role(*roleOpt),
 					},
 					*sendEmailOpt,
-				)
+				))
 				display(conch.GetBuildUsers(*buildNameArg))
 			}
 		})
 
-		cmd.Command("remove rm", "remove a user from an build", func(cmd *cli.Cmd) {
+		cmd.Command("remove rm", "remove a user from a build", func(cmd *cli.Cmd) {
 			userEmailArg := cmd.StringArg(
 				"EMAIL",
 				"",
@@ -211,20 +210,20 @@ func buildCmd(cmd *cli.Cmd) {
 			)
 			cmd.Spec = "EMAIL [OPTIONS]"
 			cmd.Action = func() {
-				conch.DeleteBuildUser(*buildNameArg, *userEmailArg, *sendEmailOpt)
+				fatalIf(conch.DeleteBuildUser(*buildNameArg, *userEmailArg, *sendEmailOpt))
 				display(conch.GetBuildUsers(*buildNameArg))
 			}
 		})
 	})
 
 	cmd.Command("organizations orgs", "Manage organizations in a specific build", func(cmd *cli.Cmd) {
-		cmd.Command("get ls", "Get a list of organizations in an build", func(cmd *cli.Cmd) {
+		cmd.Command("get ls", "Get a list of organizations in a build", func(cmd *cli.Cmd) {
 			cmd.Action = func() {
 				display(conch.GetAllBuildOrganizations(*buildNameArg))
 			}
 		})
 
-		cmd.Command("add", "Add a organization to an build", func(cmd *cli.Cmd) {
+		cmd.Command("add", "Add an organization to a build", func(cmd *cli.Cmd) {
 			orgNameArg := cmd.StringArg(
 				"NAME",
 				"",
@@ -246,7 +245,7 @@ func buildCmd(cmd *cli.Cmd) {
 			cmd.Spec = "NAME [OPTIONS]"
 			cmd.Action = func() {
 				if !okBuildRole(*roleOpt) {
-					fatalIf(fmt.Errorf(
+					fatalIf(validationError(
 						"'role' value must be one of: %s",
 						prettyBuildRoleList(),
 					))
@@ -254,12 +253,12 @@ func buildCmd(cmd *cli.Cmd) {
 				org, e := conch.GetOrganizationByName(*orgNameArg)
 				fatalIf(e)
 
-				conch.AddBuildOrganization(*buildNameArg, types.BuildAddOrganization{
-					org.ID,
-					types.Role(*roleOpt),
+				fatalIf(conch.AddBuildOrganization(*buildNameArg, types.BuildAddOrganization{
+					OrganizationID: org.ID,
+					Role:           types.Role(*roleOpt),
 				},
 					*sendEmailOpt,
-				)
+				))
 				display(conch.GetAllBuildOrganizations(*buildNameArg))
 			}
 		})
@@ -278,10 +277,10 @@ func buildCmd(cmd *cli.Cmd) {
 			)
 			cmd.Spec = "EMAIL [OPTIONS]"
 			cmd.Action = func() {
-				conch.DeleteBuildOrganization(*buildNameArg,
+				fatalIf(conch.DeleteBuildOrganization(*buildNameArg,
 					*orgNameArg,
 					*sendEmailOpt,
-				)
+				))
 				display(conch.GetAllBuildOrganizations(*buildNameArg))
 			}
 		})
@@ -291,11 +290,11 @@ func buildCmd(cmd *cli.Cmd) {
 		// list by default
 		cmd.Action = func() { display(conch.GetAllBuildDevices(*buildNameArg)) }
 
-		cmd.Command("get ls", "Get a list of devices in an build", func(cmd *cli.Cmd) {
+		cmd.Command("get ls", "Get a list of devices in a build", func(cmd *cli.Cmd) {
 			cmd.Action = func() { display(conch.GetAllBuildDevices(*buildNameArg)) }
 		})
 
-		cmd.Command("add", "Add a device to an build", func(cmd *cli.Cmd) {
+		cmd.Command("add", "Add a device to a build", func(cmd *cli.Cmd) {
 			deviceIDArg := cmd.StringArg(
 				"ID",
 				"",
@@ -304,7 +303,7 @@ func buildCmd(cmd *cli.Cmd) {
 
 			cmd.Spec = "ID [OPTIONS]"
 			cmd.Action = func() {
-				conch.AddBuildDeviceByName(*buildNameArg, *deviceIDArg)
+				fatalIf(conch.AddBuildDeviceByName(*buildNameArg, *deviceIDArg))
 				display(conch.GetAllBuildDevices(*buildNameArg))
 			}
 		})
@@ -324,7 +323,7 @@ func buildCmd(cmd *cli.Cmd) {
 				d, e := conch.GetDeviceBySerial(*deviceIDArg)
 				fatalIf(e)
 
-				conch.DeleteBuildDeviceByID(b.ID, d.ID)
+				fatalIf(conch.DeleteBuildDeviceByID(b.ID, d.ID))
 				display(conch.GetAllBuildDevices(*buildNameArg))
 			}
 		})
@@ -334,11 +333,11 @@ func buildCmd(cmd *cli.Cmd) {
 		// default to list
 		cmd.Action = func() { display(conch.GetBuildRacks(*buildNameArg)) }
 
-		cmd.Command("get ls", "Get a list of racks in an build", func(cmd *cli.Cmd) {
+		cmd.Command("get ls", "Get a list of racks in a build", func(cmd *cli.Cmd) {
 			cmd.Action = func() { display(conch.GetBuildRacks(*buildNameArg)) }
 		})
 
-		cmd.Command("add", "Add a rack to an build", func(cmd *cli.Cmd) {
+		cmd.Command("add", "Add a rack to a build", func(cmd *cli.Cmd) {
 			rackIDArg := cmd.StringArg(
 				"ID",
 				"",
@@ -347,7 +346,7 @@ func buildCmd(cmd *cli.Cmd) {
 
 			cmd.Spec = "ID [OPTIONS]"
 			cmd.Action = func() {
-				conch.AddBuildRackByID(*buildNameArg, *rackIDArg)
+				fatalIf(conch.AddBuildRackByID(*buildNameArg, *rackIDArg))
 				display(conch.GetBuildRacks(*buildNameArg))
 			}
 		})
@@ -361,7 +360,7 @@ func buildCmd(cmd *cli.Cmd) {
 
 			cmd.Spec = "ID [OPTIONS]"
 			cmd.Action = func() {
-				conch.DeleteBuildRackByID(*buildNameArg, *rackIDArg)
+				fatalIf(conch.DeleteBuildRackByID(*buildNameArg, *rackIDArg))
 				display(conch.GetBuildRacks(*buildNameArg))
 			}
 		})
@@ -1,12 +1,16 @@
 package cli
 
 import (
-	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/logger"
+	"github.com/joyent/kosh/template"
 )
 
 const (
@@ -14,11 +18,9 @@ const (
 	stagingURL    = "https://staging.conch.joyent.us"
 )
 
-func fatalIf(e error) {
-	if e != nil {
-		fmt.Println(e)
-		cli.Exit(1)
-	}
+func isTerminal(f *os.File) bool {
+	fi, e := f.Stat()
+	return e == nil && fi.Mode()&os.ModeCharDevice != 0
 }
 
 func getInputReader(filePathArg string) (io.Reader, error) {
@@ -30,17 +32,41 @@ func getInputReader(filePathArg string) (io.Reader, error) {
 
 var config Config
 
+// logFiles holds the log files already opened, so that --watch doesn't open
+// the file again on every poll
+var logFiles = map[string]*os.File{}
+
+func openLogFile(path string) (*os.File, error) {
+	if f, ok := logFiles[path]; ok {
+		return f, nil
+	}
+	f, e := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if e != nil {
+		return nil, e
+	}
+	logFiles[path] = f
+	return f, nil
+}
+
+// builtinCommands holds the names of the built in commands of the most
+// recently built app. Plugins and aliases can't shadow these.
+var builtinCommands map[string]bool
+
+// pluginCommands holds the paths of the plugins of the most recently built
+// app, by name. Aliases can't shadow these.
+var pluginCommands map[string]string
+
 func (c Config) requireAuth() {
 	if c.ConchToken == "" {
-		fmt.Println("Need to provide --token or set KOSH_TOKEN")
-		cli.Exit(1)
+		fatalIf(authError("Need to provide --token or set KOSH_TOKEN"))
 	}
 }
 
 func (c Config) requireSysAdmin() {
-	if !c.ConchClient().IsSysAdmin() {
-		fmt.Println("This action requires Conch systems administrator privileges")
-		cli.Exit(1)
+	u, e := c.ConchClient().GetCurrentUser()
+	fatalIf(e)
+	if !u.IsAdmin {
+		fatalIf(authError("This action requires Conch systems administrator privileges"))
 	}
 }
 
@@ -49,7 +75,8 @@ func NewApp(c Config) *cli.Cli {
 	config = c
 
 	app := cli.App("kosh", "Command line interface for Conch")
-	app.Spec = "[-dejutvV]"
+	app.LongDesc = "Command line interface for Conch\n\n" + exitCodesHelp
+	app.Spec = "[-dejutvV] [--columns] [--sort-by] [--no-headers] [--wide] [--watch] [--color] [--tz] [--time-format] [--debug-unsafe] [--log-file] [--log-format] [--stats]"
 
 	app.Version("V version", config.Version)
 
@@ -95,31 +122,126 @@ func NewApp(c Config) *cli.Cli {
 		EnvVar: "KOSH_VERBOSE_MODE KOSH_VERBOSE", // TODO in 4.0 remove KOSH_VERBOSE_MODE
 	})
 
-	app.Command("admin", "System Administration Commands", adminCmd)
-	app.Command("build b", "Work with a specific build", buildCmd)
-	app.Command("builds bs", "Work with builds", buildsCmd)
-	app.Command("datacenter dc", "Deal with a single datacenter", datacenterCmd)
-	app.Command("datacenters dcs", "Work with the datacenters you have access to", datacentersCmd)
-	app.Command("device d", "Perform actions against a single device", deviceCmd)
-	app.Command("device-report dr", "Deal with device reports", deviceReportCmd)
-	app.Command("devices ds", "Commands for dealing with multiple devices", devicesCmd)
-	app.Command("hardware h", "Work with hardware profiles and vendors", hardwareCmd)
-	app.Command("organization org", "Work with a specific organization", organizationCmd)
-	app.Command("organizations orgs", "Work with organizations", organizationsCmd)
-	app.Command("rack r", "Work with a single rack", rackCmd)
-	app.Command("racks rs", "Work with datacenter racks", racksCmd)
-	app.Command("relay", "Perform actions against a single relay", relayCmd)
-	app.Command("relays", "Perform actions against the whole list of relays", relaysCmd)
-	app.Command("roles", "Work with datacenter rack roles", rolesCmd)
-	app.Command("role", "Work with a single rack role", roleCmd)
-	app.Command("room", "Deal with a single datacenter room", roomCmd)
-	app.Command("rooms", "Work with datacenter rooms", roomsCmd)
-	app.Command("schema", "Get the server JSON Schema for a given request or response", schemaCmd)
-	app.Command("user u", "Commands for dealing with the current user (you)", userCmd)
-	app.Command("validation v", "Work with validations", validationCmd)
-	app.Command("whoami", "Display details of the current user", whoamiCmd)
-
-	app.Command("version", "Get more detailed version info than --version", func(cmd *cli.Cmd) {
+	app.BoolPtr(&config.Logger.Unsafe, cli.BoolOpt{
+		Name:  "debug-unsafe",
+		Value: false,
+		Desc:  "Enable Debugging output without redacting tokens and passwords. Do not share this output",
+	})
+
+	app.StringPtr(&config.LogFile, cli.StringOpt{
+		Name:   "log-file",
+		Value:  "",
+		Desc:   "Append a log of every request, and anything else logged, to this file",
+		EnvVar: "KOSH_LOG_FILE",
+	})
+
+	app.StringPtr(&config.Logger.Format, cli.StringOpt{
+		Name:   "log-format",
+		Value:  logger.FormatText,
+		Desc:   "Format of log output: text or json",
+		EnvVar: "KOSH_LOG_FORMAT",
+	})
+
+	app.StringPtr(&config.Stats, cli.StringOpt{
+		Name:  "stats",
+		Value: "",
+		Desc:  "After the command, write a summary of the API requests it made to STDERR: table (--stats alone) or json",
+	})
+
+	app.StringPtr(&config.Columns, cli.StringOpt{
+		Name:   "columns",
+		Value:  "",
+		Desc:   "Comma separated list of the table columns to display, by header name",
+		EnvVar: "KOSH_COLUMNS",
+	})
+
+	app.StringPtr(&config.SortBy, cli.StringOpt{
+		Name:   "sort-by",
+		Value:  "",
+		Desc:   "Sort table output by the named column. Append ':desc' to reverse the order",
+		EnvVar: "KOSH_SORT_BY",
+	})
+
+	app.BoolPtr(&config.NoHeaders, cli.BoolOpt{
+		Name:   "no-headers",
+		Value:  false,
+		Desc:   "Do not display the header row in table output",
+		EnvVar: "KOSH_NO_HEADERS",
+	})
+
+	app.BoolPtr(&config.Wide, cli.BoolOpt{
+		Name:   "wide",
+		Value:  false,
+		Desc:   "Wide output, display full UUIDs rather than the short form",
+		EnvVar: "KOSH_WIDE",
+	})
+
+	app.StringPtr(&config.Watch, cli.StringOpt{
+		Name:  "watch",
+		Value: "",
+		Desc:  "Re-run the command every interval (--watch alone is every " + defaultWatchInterval + "), highlighting what changed",
+	})
+
+	app.StringPtr(&config.Color, cli.StringOpt{
+		Name:   "color",
+		Value:  "auto",
+		Desc:   "Colour the output: auto (only on a terminal, unless NO_COLOR is set), always or never",
+		EnvVar: "KOSH_COLOR",
+	})
+
+	app.StringPtr(&config.TimeZone, cli.StringOpt{
+		Name:   "tz",
+		Value:  "",
+		Desc:   "Time zone to display times in, e.g. UTC or America/Los_Angeles. Defaults to the local time zone",
+		EnvVar: "KOSH_TZ",
+	})
+
+	app.StringPtr(&config.TimeFormat, cli.StringOpt{
+		Name:   "time-format",
+		Value:  template.TimeFormatLocal,
+		Desc:   "Format for displaying times: local, rfc3339, relative or unix",
+		EnvVar: "KOSH_TIME_FORMAT",
+	})
+
+	// builtins tracks the names of the built in commands so that plugins and
+	// aliases can't shadow them
+	builtins := map[string]bool{docsCommand: true}
+	builtinCommands = builtins
+	command := func(name, desc string, init cli.CmdInitializer) {
+		for _, n := range strings.Fields(name) {
+			builtins[n] = true
+		}
+		app.Command(name, desc, init)
+	}
+
+	command("admin", "System Administration Commands", adminCmd)
+	command("api", "Make an authenticated request to any API endpoint", apiCmd)
+	command("build b", "Work with a specific build", buildCmd)
+	command("builds bs", "Work with builds", buildsCmd)
+	command("datacenter dc", "Deal with a single datacenter", datacenterCmd)
+	command("datacenters dcs", "Work with the datacenters you have access to", datacentersCmd)
+	command("device d", "Perform actions against a single device", deviceCmd)
+	command("doctor", "Check the configuration and the connection to the API", doctorCmd)
+	command("device-report dr", "Deal with device reports", deviceReportCmd)
+	command("devices ds", "Commands for dealing with multiple devices", devicesCmd)
+	command("hardware h", "Work with hardware profiles and vendors", hardwareCmd)
+	command("organization org", "Work with a specific organization", organizationCmd)
+	command("organizations orgs", "Work with organizations", organizationsCmd)
+	command("rack r", "Work with a single rack", rackCmd)
+	command("racks rs", "Work with datacenter racks", racksCmd)
+	command("relay", "Perform actions against a single relay", relayCmd)
+	command("relays", "Perform actions against the whole list of relays", relaysCmd)
+	command("roles", "Work with datacenter rack roles", rolesCmd)
+	command("role", "Work with a single rack role", roleCmd)
+	command("room", "Deal with a single datacenter room", roomCmd)
+	command("rooms", "Work with datacenter rooms", roomsCmd)
+	command("schema", "Get the server JSON Schema for a given request or response", schemaCmd)
+	command("templates", "Work with the templates used for text output", templatesCmd)
+	command("user u", "Commands for dealing with the current user (you)", userCmd)
+	command("validation v", "Work with validations", validationCmd)
+	command("whoami", "Display details of the current user", whoamiCmd)
+
+	command("version", "Get more detailed version info than --version", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
 			conch := config.ConchClient()
 			display := config.Renderer()
@@ -132,7 +254,38 @@ func NewApp(c Config) *cli.Cli {
 		}
 	})
 
+	command("alias aliases", "Manage command aliases, kept in the kosh config file", aliasCmd(builtins))
+
+	var found plugins
+	command("plugin plugins", "Work with external kosh-<name> commands found on the PATH", func(cmd *cli.Cmd) {
+		pluginListCmd(found)(cmd)
+	})
+
+	found = findPlugins(os.Getenv("PATH"), builtins)
+	pluginCommands = map[string]string{}
+	for _, p := range found {
+		if p.Status == "ok" {
+			pluginCommands[p.Name] = p.Path
+			app.Command(p.Name, "Plugin: "+p.Path, pluginCmd(p))
+		}
+	}
+
 	app.Before = func() {
+		if config.Logger.Unsafe {
+			config.Logger.LevelDebug = true
+		}
+
+		switch config.Logger.Format {
+		case logger.FormatText, logger.FormatJSON:
+		default:
+			fatalIf(usageError("--log-format must be one of text, json: got '%s'", config.Logger.Format))
+		}
+		if config.LogFile != "" {
+			f, e := openLogFile(config.LogFile)
+			fatalIf(e)
+			config.Logger.File = f
+		}
+
 		if config.ConchURL == "" {
 			switch config.ConchENV {
 			case "production":
@@ -140,13 +293,101 @@ func NewApp(c Config) *cli.Cli {
 			case "staging":
 				config.ConchURL = stagingURL
 			default:
-				fatalIf(errors.New("environment not one of production, staging, edge: perhaps you want --url?"))
+				fatalIf(usageError("environment not one of production, staging, edge: perhaps you want --url?"))
 			}
 		}
 
+		fatalIf(config.startStats())
+		config.inflight = conch.NewInflight()
+
+		template.FullUUIDs = config.Wide
+
+		if !template.ValidTimeFormat(config.TimeFormat) {
+			fatalIf(usageError("--time-format must be one of local, rfc3339, relative, unix: got '%s'", config.TimeFormat))
+		}
+		template.TimeFormat = config.TimeFormat
+		if config.TimeZone != "" {
+			loc, e := time.LoadLocation(config.TimeZone)
+			if e != nil {
+				fatalIf(usageError("unknown time zone '%s': %s", config.TimeZone, e))
+			}
+			template.Location = loc
+		}
+
+		config.terminal = isTerminal(os.Stdout)
+		if config.terminal {
+			config.height, config.width = terminalSize()
+		}
+		colorize, e := colorEnabled(config.Color, config.terminal)
+		fatalIf(e)
+		config.colorize = colorize
+		template.NoColor = !colorize
+		template.Dir = templatesDir()
+
+		if config.Watch != "" {
+			interval, e := parseWatchInterval(config.Watch)
+			fatalIf(e)
+			config.WatchInterval = interval
+			config.watch = &watchCapture{}
+		}
+
+		if _, e := config.TableOptions(); e != nil {
+			fatalIf(e)
+		}
+
 		config.Debug("Starting App")
 		config.Info(config)
 	}
 
+	app.After = func() {
+		config.writeStats(os.Stderr)
+	}
+
 	return app
 }
+
+// Run builds the kosh app and runs it with the given arguments. Aliases
+// from the config file are expanded first. If --watch was given, the command
+// is re-run on the requested interval until it is interrupted.
+func Run(c Config, args []string) error {
+	args = normalizeStatsArgs(normalizeWatchArgs(args))
+
+	if i := commandIndex(args); i >= 0 && args[i] == docsCommand {
+		if e := runDocs(c, args[i+1:]); e != nil {
+			fmt.Fprintln(os.Stderr, e)
+			os.Exit(exitCode(e))
+		}
+		return nil
+	}
+
+	app := NewApp(c)
+
+	args, e := applyAliases(args)
+	if e != nil {
+		fmt.Fprintln(os.Stderr, e)
+		os.Exit(exitCode(e))
+	}
+	if args == nil {
+		return nil
+	}
+	args = passToPlugin(args, pluginCommands)
+
+	c.args = args
+	config.args = args
+	if e := runWatched(app, args); e != nil {
+		return e
+	}
+	if config.watch == nil {
+		return nil
+	}
+
+	w := newWatcher(config, os.Stdout, isTerminal(os.Stdout), args)
+	for {
+		w.poll(config.watch)
+		time.Sleep(w.config.WatchInterval)
+
+		if e := runWatched(NewApp(c), args); e != nil {
+			return e
+		}
+	}
+}
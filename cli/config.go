@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/joyent/kosh/conch"
 	"github.com/joyent/kosh/logger"
@@ -25,6 +26,33 @@ type Config struct {
 
 	OutputJSON bool
 
+	Columns   string
+	SortBy    string
+	NoHeaders bool
+	Wide      bool
+
+	Watch         string
+	WatchInterval time.Duration
+	watch         *watchCapture
+
+	TimeZone   string
+	TimeFormat string
+
+	LogFile string
+
+	Stats    string
+	stats    *conch.Stats
+	inflight *conch.Inflight
+
+	// args is the command line being run, after alias expansion
+	args []string
+
+	Color    string
+	colorize bool
+	terminal bool
+	width    int
+	height   int
+
 	logger.Logger
 }
 
@@ -49,16 +77,33 @@ const configTemplate = `
 * ConchToken: {{ .ConchToken }}
 
 * OutputJSON: {{ .OutputJSON }}
+* Columns: {{ .Columns }}
+* SortBy: {{ .SortBy }}
+* NoHeaders: {{ .NoHeaders }}
+* Wide: {{ .Wide }}
+* Watch: {{ .WatchInterval }}
+* Color: {{ .Color }}
+* TimeZone: {{ .TimeZone }}
+* TimeFormat: {{ .TimeFormat }}
 
 Logger
 
 * Debug {{ .Logger.LevelDebug  }}
 * Info {{ .Logger.LevelInfo  }}
+* Unsafe {{ .Logger.Unsafe }}
+* Format {{ .Logger.Format }}
+* File {{ .LogFile }}
+* Stats {{ .Stats }}
 ---
 `
 
-// String returns a string implementation of the config object
+// String returns a string implementation of the config object. The token is
+// redacted unless --debug-unsafe was given.
 func (c Config) String() string {
+	if c.ConchToken != "" && !c.Logger.Unsafe {
+		c.ConchToken = logger.Redacted
+	}
+
 	t, err := template.NewTemplate().Parse(configTemplate)
 	if err != nil {
 		log.Fatal(err)
@@ -79,6 +124,8 @@ func (c Config) ConchClient() *conch.Client {
 		conch.AuthToken(c.ConchToken),
 		conch.UserAgent(fmt.Sprintf("kosh %s", c.GitRev)),
 		conch.Logger(c.Logger),
+		conch.RecordStats(c.stats),
+		conch.ShareInflight(c.inflight),
 	)
 }
 
@@ -86,9 +133,21 @@ func (c Config) ConchClient() *conch.Client {
 // the output
 type Renderer func(interface{}, error)
 
-// Renderer returns a function that will render to STDOUT
+// Renderer returns a function that will render to STDOUT. In --watch mode the
+// data is instead captured for the watch loop to diff and draw. When STDOUT
+// is a terminal, output longer than the screen is sent through $PAGER.
 func (c Config) Renderer() Renderer {
-	return c.RenderTo(os.Stdout)
+	if c.watch != nil {
+		return c.watch.display
+	}
+	if !c.terminal {
+		return c.RenderTo(os.Stdout)
+	}
+	return func(i interface{}, e error) {
+		buf := &strings.Builder{}
+		c.RenderTo(buf)(i, e)
+		c.page(buf.String())
+	}
 }
 
 func renderJSON(i interface{}) string {
@@ -97,13 +156,34 @@ func renderJSON(i interface{}) string {
 	return string(b)
 }
 
+// TableOptions returns the column selection, sorting and terminal fitting
+// options for table output
+func (c Config) TableOptions() (tables.Options, error) {
+	column, descending, e := tables.ParseSortBy(c.SortBy)
+	if e != nil {
+		return tables.Options{}, e
+	}
+	opts := tables.Options{
+		Columns:    tables.ParseColumns(c.Columns),
+		SortBy:     column,
+		Descending: descending,
+		NoHeaders:  c.NoHeaders,
+	}
+	if c.terminal && !c.Wide {
+		opts.MaxWidth = c.width
+	}
+	if c.colorize {
+		opts.Color = colorCell
+	}
+	return opts, nil
+}
+
 // RenderTo returns a function tha renders to a given io.Writer based on the
 // configuraton and datatype
 func (c Config) RenderTo(w io.Writer) func(interface{}, error) {
 	return func(i interface{}, e error) {
-		if e != nil {
-			fmt.Fprintln(w, e)
-		}
+		fatalIf(e)
+
 		if c.OutputJSON {
 			c.Debug("Outputting JSON")
 			fmt.Fprintln(w, renderJSON(i))
@@ -116,7 +196,13 @@ func (c Config) RenderTo(w io.Writer) func(interface{}, error) {
 
 			fmt.Fprintln(w, s)
 		case tables.Tabulable:
-			fmt.Fprintln(w, tables.Render(t))
+			opts, e := c.TableOptions()
+			fatalIf(e)
+
+			s, e := tables.RenderWith(t, opts)
+			fatalIf(e)
+
+			fmt.Fprintln(w, s)
 		case fmt.Stringer:
 			fmt.Fprintln(w, t)
 		default:
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configDir returns the directory kosh keeps its configuration in,
+// $XDG_CONFIG_HOME/kosh, falling back to ~/.config/kosh
+func configDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "kosh")
+	}
+	home, e := os.UserHomeDir()
+	if e != nil {
+		return filepath.Join(".config", "kosh")
+	}
+	return filepath.Join(home, ".config", "kosh")
+}
+
+// configFilePath returns the path to the kosh config file. KOSH_CONFIG
+// overrides the default of config.yaml in the configDir
+func configFilePath() string {
+	if path := os.Getenv("KOSH_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(configDir(), "config.yaml")
+}
+
+// ConfigFile is the structure of the kosh config file
+type ConfigFile struct {
+	// Aliases maps an alias name onto the command line it expands to
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+}
+
+// loadConfigFile reads the config file at the given path. A missing file is
+// not an error, it results in an empty ConfigFile.
+func loadConfigFile(path string) (ConfigFile, error) {
+	cf := ConfigFile{}
+
+	b, e := ioutil.ReadFile(path)
+	if os.IsNotExist(e) {
+		return cf, nil
+	}
+	if e != nil {
+		return cf, e
+	}
+
+	if e := yaml.Unmarshal(b, &cf); e != nil {
+		return cf, validationError("unable to parse %s: %s", path, e)
+	}
+	return cf, nil
+}
+
+// save writes the config file to the given path, creating its directory if
+// necessary
+func (cf ConfigFile) save(path string) error {
+	b, e := yaml.Marshal(cf)
+	if e != nil {
+		return e
+	}
+	if e := os.MkdirAll(filepath.Dir(path), 0700); e != nil {
+		return e
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
@@ -20,7 +20,9 @@ func TestRender(t *testing.T) {
 	// TODO replace with fixtures
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("null"))
 	}))
+	defer ts.Close()
 	conch := conch.New(conch.API(ts.URL))
 
 	tests := []struct {
@@ -208,7 +210,6 @@ func TestRender(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			defer ts.Close()
 			test.Do()
 			assert.NotEmpty(t, buffer.String())
 			buffer.Reset()
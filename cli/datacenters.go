@@ -1,9 +1,6 @@
 package cli
 
 import (
-	"errors"
-	"fmt"
-
 	cli "github.com/jawher/mow.cli"
 	"github.com/joyent/kosh/conch"
 	"github.com/joyent/kosh/conch/types"
@@ -41,24 +38,21 @@ func datacentersCmd(cmd *cli.Cmd) {
 			// '--vendor ""' which will pass the cli lib's requirement
 			// check but is still crap
 			if *vendorOpt == "" {
-				fmt.Println("--vendor is required")
-				cli.Exit(1)
+				fatalIf(usageError("--vendor is required"))
 			}
 			if *regionOpt == "" {
-				fmt.Println("--region is required")
-				cli.Exit(1)
+				fatalIf(usageError("--region is required"))
 			}
 			if *locationOpt == "" {
-				fmt.Println("--location is required")
-				cli.Exit(1)
+				fatalIf(usageError("--location is required"))
 			}
 
-			conch.CreateDatacenter(types.DatacenterCreate{
+			fatalIf(conch.CreateDatacenter(types.DatacenterCreate{
 				Location:   types.NonEmptyString(*locationOpt),
 				Region:     types.NonEmptyString(*regionOpt),
 				Vendor:     types.NonEmptyString(*vendorOpt),
 				VendorName: types.NonEmptyString(*vendorNameOpt),
-			})
+			}))
 		}
 	})
 }
@@ -87,7 +81,7 @@ func datacenterCmd(cmd *cli.Cmd) {
 		fatalIf(e)
 
 		if (dc == types.Datacenter{}) {
-			fatalIf(errors.New("couldn't find datacenter"))
+			fatalIf(notFoundError("couldn't find datacenter"))
 		}
 	}
 
@@ -97,7 +91,7 @@ func datacenterCmd(cmd *cli.Cmd) {
 
 	cmd.Command("delete", "Delete a single datacenter", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
-			conch.DeleteDatacenter(dc.ID)
+			fatalIf(conch.DeleteDatacenter(dc.ID))
 			display(conch.GetAllDatacenters())
 		}
 	})
@@ -140,14 +134,14 @@ func datacenterCmd(cmd *cli.Cmd) {
 			}
 
 			if count == 0 {
-				fatalIf(errors.New("one option must be provided"))
+				fatalIf(usageError("one option must be provided"))
 			}
-			conch.UpdateDatacenter(dc.ID, types.DatacenterUpdate{
+			fatalIf(conch.UpdateDatacenter(dc.ID, types.DatacenterUpdate{
 				Location:   types.NonEmptyString(*locationOpt),
 				Region:     types.NonEmptyString(*regionOpt),
 				Vendor:     types.NonEmptyString(*vendorOpt),
 				VendorName: types.NonEmptyString(*vendorNameOpt),
-			})
+			}))
 		}
 	})
 
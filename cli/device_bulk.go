@@ -0,0 +1,320 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// defaultBulkConcurrency is how many requests kosh devices bulk makes at once
+const defaultBulkConcurrency = 8
+
+// bulkRow is a single device to change, with the value for it if the input
+// gave one. Row is its position among the devices in the input, from 1.
+type bulkRow struct {
+	Row    int
+	Device string
+	Value  string
+}
+
+// readBulkRows reads the devices to change, one serial number or ID per line.
+// A line may also be CSV, with the value for that device in the second
+// column. Blank lines, lines starting with # and a header row naming the
+// first column device, serial or id are skipped.
+func readBulkRows(r io.Reader) ([]bulkRow, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows := []bulkRow{}
+	for {
+		record, e := reader.Read()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, validationError("unable to read devices: %s", e)
+		}
+		device := strings.TrimSpace(record[0])
+		if device == "" {
+			continue
+		}
+		if len(rows) == 0 {
+			switch strings.ToLower(device) {
+			case "device", "serial", "serial_number", "id":
+				continue
+			}
+		}
+
+		row := bulkRow{Row: len(rows) + 1, Device: device}
+		if len(record) > 1 {
+			row.Value = strings.TrimSpace(record[1])
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// bulkResult is the outcome of changing a single device
+type bulkResult struct {
+	bulkRow
+	Error error
+}
+
+type bulkResults []bulkResult
+
+func (b bulkResults) Len() int           { return len(b) }
+func (b bulkResults) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b bulkResults) Less(i, j int) bool { return b[i].Row < b[j].Row }
+
+// Headers returns the list of headers for the table view
+func (b bulkResults) Headers() []string {
+	return []string{
+		"Row",
+		"Device",
+		"Value",
+		"Status",
+		"Error",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (b bulkResults) ForEach(do func([]string)) {
+	for _, r := range b {
+		status, message := "ok", ""
+		if r.Error != nil {
+			status, message = "error", r.Error.Error()
+		}
+		do([]string{
+			fmt.Sprintf("%d", r.Row),
+			r.Device,
+			r.Value,
+			status,
+			message,
+		})
+	}
+}
+
+// failures returns the results that failed, in input order
+func (b bulkResults) failures() bulkResults {
+	failed := bulkResults{}
+	for _, r := range b {
+		if r.Error != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// runBulk applies the change to every row, with at most concurrency changes
+// in flight at once. Rows without a value of their own are given
+// defaultValue. The results are in the same order as the rows.
+func runBulk(rows []bulkRow, defaultValue string, concurrency int, apply func(bulkRow) error) bulkResults {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(bulkResults, len(rows))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				row := rows[i]
+				if row.Value == "" {
+					row.Value = defaultValue
+				}
+				results[i] = bulkResult{bulkRow: row, Error: apply(row)}
+			}
+		}()
+	}
+	for i := range rows {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// writeFailures writes the devices that failed, and their values, in the
+// format readBulkRows reads, so that they can be retried with --input
+func writeFailures(path string, failed bulkResults) error {
+	f, e := os.Create(path)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	for _, r := range failed {
+		if e := w.Write([]string{r.Device, r.Value}); e != nil {
+			return e
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// bulkAction sets up a kosh devices bulk subcommand. valueName, if not empty,
+// is the name of the optional argument giving the value for every device
+// without one of its own. apply makes the change to a single device.
+func bulkAction(valueName, valueDesc string, apply func(c *conch.Client, row bulkRow) error) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		var value *string
+		if valueName != "" {
+			value = cmd.StringArg(valueName, "", valueDesc+". Overridden by a value in the input")
+		}
+		input := cmd.StringOpt("i input", "-", "File of device serial numbers or IDs, one per line or CSV of DEVICE,VALUE ('-' for STDIN)")
+		concurrency := cmd.IntOpt("c concurrency", defaultBulkConcurrency, "Number of devices to change at once")
+		failures := cmd.StringOpt("failures", "", "Write the devices that failed to this file, to retry with --input")
+
+		cmd.Spec = "[OPTIONS]"
+		if valueName != "" {
+			cmd.Spec = "[OPTIONS] [" + valueName + "] [OPTIONS]"
+		}
+
+		cmd.Action = func() {
+			r, e := getInputReader(*input)
+			fatalIf(e)
+			rows, e := readBulkRows(r)
+			fatalIf(e)
+			if len(rows) == 0 {
+				fatalIf(usageError("no devices given in %s", *input))
+			}
+
+			defaultValue := ""
+			if value != nil {
+				defaultValue = *value
+			}
+
+			client := config.ConchClient()
+			results := runBulk(rows, defaultValue, *concurrency, func(row bulkRow) error {
+				return apply(client, row)
+			})
+			config.Renderer()(results, nil)
+
+			failed := results.failures()
+			if len(failed) == 0 {
+				return
+			}
+			if *failures != "" {
+				fatalIf(writeFailures(*failures, failed))
+				fmt.Fprintf(os.Stderr, "The devices that failed were written to %s. Retry them with --input %s\n", *failures, *failures)
+			}
+			fatalIf(fmt.Errorf("%d of %d devices failed", len(failed), len(results)))
+		}
+	}
+}
+
+// requireValue fails a row that has no value, from either the input or the
+// command line
+func requireValue(row bulkRow) error {
+	if row.Value == "" {
+		return validationError("no value given for %s", row.Device)
+	}
+	return nil
+}
+
+// buildIDs resolves build names to IDs once each, for every worker
+type buildIDs struct {
+	mu  sync.Mutex
+	ids map[string]types.UUID
+}
+
+func (b *buildIDs) lookup(c *conch.Client, name string) (types.UUID, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if id, ok := b.ids[name]; ok {
+		return id, nil
+	}
+	build, e := c.GetBuildByName(name)
+	if e != nil {
+		return types.UUID{}, e
+	}
+	if b.ids == nil {
+		b.ids = map[string]types.UUID{}
+	}
+	b.ids[name] = build.ID
+	return build.ID, nil
+}
+
+func deviceBulkCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Make the same kind of change to many devices.
+
+The devices are read from --input (STDIN by default), one serial number or ID
+per line. To give each device its own value, use CSV: DEVICE,VALUE. A value in
+the input overrides the one given on the command line.
+
+Every device is reported in a table. If any fail, the exit status is non zero
+and --failures FILE saves them, with their values, to retry with --input FILE.`
+
+	cmd.Command("phase", "Set the phase of many devices [one of: "+prettyPhasesList()+"]", bulkAction(
+		"PHASE",
+		"Phase to set",
+		func(c *conch.Client, row bulkRow) error {
+			if e := requireValue(row); e != nil {
+				return e
+			}
+			if !okPhase(row.Value) {
+				return validationError("phase must be one of: %s", prettyPhasesList())
+			}
+			return c.SetDevicePhase(row.Device, row.Value)
+		},
+	))
+
+	cmd.Command("asset-tag", "Set the asset tag of many devices, given in the input", bulkAction(
+		"",
+		"",
+		func(c *conch.Client, row bulkRow) error {
+			if e := requireValue(row); e != nil {
+				return e
+			}
+			return c.SetDeviceAssetTag(row.Device, types.DeviceAssetTag(row.Value))
+		},
+	))
+
+	builds := &buildIDs{}
+	cmd.Command("build", "Move many devices to a build", bulkAction(
+		"BUILD",
+		"Name or ID of the build",
+		func(c *conch.Client, row bulkRow) error {
+			if e := requireValue(row); e != nil {
+				return e
+			}
+			id, e := builds.lookup(c, row.Value)
+			if e != nil {
+				return e
+			}
+			return c.SetDeviceBuild(row.Device, map[string]types.UUID{"build_id": id})
+		},
+	))
+
+	keyed := func(name, desc string, set func(c *conch.Client, id, key, value string) error) {
+		cmd.Command(name, desc, func(cmd *cli.Cmd) {
+			key := cmd.StringArg("NAME", "", "Name of the "+name)
+			bulkAction("VALUE", "Value of the "+name, func(c *conch.Client, row bulkRow) error {
+				if e := requireValue(row); e != nil {
+					return e
+				}
+				return set(c, row.Device, *key, row.Value)
+			})(cmd)
+			cmd.Spec = "[OPTIONS] NAME [VALUE] [OPTIONS]"
+		})
+	}
+	keyed("tag", "Set a tag on many devices", func(c *conch.Client, id, key, value string) error {
+		return c.SetDeviceTag(id, key, value)
+	})
+	keyed("setting", "Set a setting on many devices", func(c *conch.Client, id, key, value string) error {
+		return c.SetDeviceSetting(id, key, value)
+	})
+}
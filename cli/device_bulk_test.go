@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBulkRows(t *testing.T) {
+	rows, e := readBulkRows(strings.NewReader("serial,value\nS1\n# a comment\n\n S2 , staging\nS3,\"a, b\"\n"))
+	assert.Nil(t, e)
+	assert.Equal(t, []bulkRow{
+		{Row: 1, Device: "S1"},
+		{Row: 2, Device: "S2", Value: "staging"},
+		{Row: 3, Device: "S3", Value: "a, b"},
+	}, rows)
+
+	_, e = readBulkRows(strings.NewReader("S1,\"unterminated\n"))
+	assert.Equal(t, ExitValidation, exitCode(e))
+}
+
+func TestRunBulk(t *testing.T) {
+	rows := []bulkRow{}
+	for _, d := range []string{"S1", "S2", "BAD", "S4", "S5"} {
+		rows = append(rows, bulkRow{Row: len(rows) + 1, Device: d})
+	}
+	rows[1].Value = "own"
+
+	mu := sync.Mutex{}
+	running, most := 0, 0
+	results := runBulk(rows, "default", 2, func(row bulkRow) error {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+
+		if row.Device == "BAD" {
+			return errors.New("not found")
+		}
+		return nil
+	})
+
+	assert.LessOrEqual(t, most, 2)
+	assert.Len(t, results, 5)
+	for i, r := range results {
+		assert.Equal(t, rows[i].Device, r.Device, "results are in input order")
+	}
+	assert.Equal(t, "default", results[0].Value)
+	assert.Equal(t, "own", results[1].Value)
+
+	failed := results.failures()
+	assert.Len(t, failed, 1)
+	assert.Equal(t, "BAD", failed[0].Device)
+
+	path := filepath.Join(t.TempDir(), "failed.csv")
+	assert.Nil(t, writeFailures(path, failed))
+	f, e := os.Open(path)
+	assert.Nil(t, e)
+	defer f.Close()
+	retry, e := readBulkRows(f)
+	assert.Nil(t, e)
+	assert.Equal(t, []bulkRow{{Row: 1, Device: "BAD", Value: "default"}}, retry)
+}
+
+func TestRequireValue(t *testing.T) {
+	assert.Nil(t, requireValue(bulkRow{Device: "S1", Value: "x"}))
+	assert.Equal(t, ExitValidation, exitCode(requireValue(bulkRow{Device: "S1"})))
+}
@@ -0,0 +1,523 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// findToken is a single token of a device filter expression. Parentheses are
+// only operators when they weren't quoted.
+type findToken struct {
+	text string
+	op   bool
+}
+
+// lexFilter splits a filter expression into tokens. Words are separated by
+// whitespace and parentheses, and may be quoted as in the shell.
+func lexFilter(s string) ([]findToken, error) {
+	tokens := []findToken{}
+	word := &strings.Builder{}
+	inWord := false
+	var quote rune
+
+	end := func() {
+		if inWord {
+			tokens = append(tokens, findToken{text: word.String()})
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '(' || r == ')':
+			end()
+			tokens = append(tokens, findToken{text: string(r), op: true})
+		case r == ' ' || r == '\t' || r == '\n':
+			end()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, usageError("unterminated quote in '%s'", s)
+	}
+	end()
+	return tokens, nil
+}
+
+// findContext supplies what a devicePredicate needs beyond the device itself.
+// Tags and hardware products are fetched on demand and remembered.
+type findContext struct {
+	now time.Time
+
+	// getTags fetches the tags of a device
+	getTags func(id string) (types.DeviceSettings, error)
+	// getProduct resolves a hardware product name, SKU or alias to its ID
+	getProduct func(name string) (types.UUID, error)
+
+	tags     map[string]types.DeviceSettings
+	products map[string]types.UUID
+}
+
+func (ctx *findContext) deviceTags(d types.Device) (types.DeviceSettings, error) {
+	id := d.ID.String()
+	if tags, ok := ctx.tags[id]; ok {
+		return tags, nil
+	}
+	tags, e := ctx.getTags(id)
+	if e != nil {
+		return nil, e
+	}
+	if ctx.tags == nil {
+		ctx.tags = map[string]types.DeviceSettings{}
+	}
+	ctx.tags[id] = tags
+	return tags, nil
+}
+
+func (ctx *findContext) productID(name string) (types.UUID, error) {
+	if id, ok := ctx.products[name]; ok {
+		return id, nil
+	}
+	id, e := ctx.getProduct(name)
+	if e != nil {
+		return id, e
+	}
+	if ctx.products == nil {
+		ctx.products = map[string]types.UUID{}
+	}
+	ctx.products[name] = id
+	return id, nil
+}
+
+// devicePredicate decides whether a device matches part of a filter
+type devicePredicate func(ctx *findContext, d types.Device) (bool, error)
+
+// globMatch reports whether s matches the shell pattern. An invalid pattern
+// only matches itself.
+func globMatch(pattern, s string) bool {
+	if ok, e := path.Match(pattern, s); e == nil {
+		return ok
+	}
+	return pattern == s
+}
+
+// parseAge parses a duration that may also be given in days (d) or weeks (w)
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n := strings.TrimSuffix(s, suffix); n != s {
+			f, e := strconv.ParseFloat(n, 64)
+			if e != nil {
+				return 0, e
+			}
+			return time.Duration(f * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// idString returns the string form of a UUID, or an empty string for an
+// unset one
+func idString(id types.UUID) string {
+	if id.UUID == uuid.Nil {
+		return ""
+	}
+	return id.String()
+}
+
+// findFields are the device fields a predicate may compare, and how to get
+// the values a device matches on
+var findFields = map[string]func(d types.Device) []string{
+	"phase":    func(d types.Device) []string { return []string{string(d.Phase)} },
+	"health":   func(d types.Device) []string { return []string{string(d.Health)} },
+	"build":    func(d types.Device) []string { return []string{d.BuildName, idString(d.BuildID)} },
+	"rack":     func(d types.Device) []string { return []string{d.RackName, idString(d.RackID)} },
+	"hostname": func(d types.Device) []string { return []string{d.Hostname} },
+	"serial":   func(d types.Device) []string { return []string{string(d.SerialNumber)} },
+}
+
+// parsePredicate parses a single comparison:
+//
+//	FIELD=PATTERN, FIELD!=PATTERN for the fields in findFields
+//	product=NAME, product!=NAME for the hardware product
+//	last_seen<AGE, last_seen>AGE
+//	tag:NAME, tag:NAME=PATTERN, tag:NAME!=PATTERN
+func parsePredicate(s string) (devicePredicate, error) {
+	if strings.HasPrefix(s, "tag:") {
+		return parseTagPredicate(strings.TrimPrefix(s, "tag:"))
+	}
+
+	i := strings.IndexAny(s, "=!<>")
+	if i <= 0 {
+		return nil, usageError("invalid filter '%s': expected FIELD=VALUE, last_seen<AGE or tag:NAME", s)
+	}
+	field, op, value := s[:i], s[i:i+1], s[i+1:]
+	if op == "!" {
+		if !strings.HasPrefix(value, "=") {
+			return nil, usageError("invalid filter '%s': expected != ", s)
+		}
+		op, value = "!=", value[1:]
+	}
+	field = strings.ToLower(field)
+
+	switch field {
+	case "last_seen":
+		if op != "<" && op != ">" {
+			return nil, usageError("last_seen must be compared with < or >, e.g. last_seen>1h: got '%s'", s)
+		}
+		age, e := parseAge(value)
+		if e != nil {
+			return nil, usageError("invalid age in '%s': %s", s, e)
+		}
+		return func(ctx *findContext, d types.Device) (bool, error) {
+			// a device that has never been seen is older than any age
+			if d.LastSeen.IsZero() {
+				return op == ">", nil
+			}
+			seen := ctx.now.Sub(d.LastSeen)
+			if op == "<" {
+				return seen < age, nil
+			}
+			return seen > age, nil
+		}, nil
+
+	case "product", "hardware":
+		if op != "=" && op != "!=" {
+			return nil, usageError("invalid filter '%s': %s must be compared with = or !=", s, field)
+		}
+		return func(ctx *findContext, d types.Device) (bool, error) {
+			id, e := uuid.FromString(value)
+			if e != nil {
+				product, e := ctx.productID(value)
+				if e != nil {
+					return false, e
+				}
+				id = product.UUID
+			}
+			return (d.HardwareProductID.UUID == id) == (op == "="), nil
+		}, nil
+	}
+
+	values, ok := findFields[field]
+	if !ok {
+		return nil, usageError(
+			"unknown field '%s' in '%s': must be one of build, hardware, health, hostname, last_seen, phase, product, rack, serial or tag:NAME",
+			field,
+			s,
+		)
+	}
+	if op != "=" && op != "!=" {
+		return nil, usageError("invalid filter '%s': %s must be compared with = or !=", s, field)
+	}
+	return func(ctx *findContext, d types.Device) (bool, error) {
+		matched := false
+		for _, v := range values(d) {
+			if v != "" && globMatch(value, v) {
+				matched = true
+				break
+			}
+		}
+		return matched == (op == "="), nil
+	}, nil
+}
+
+func parseTagPredicate(s string) (devicePredicate, error) {
+	name, value, op := s, "", ""
+	if i := strings.Index(s, "!="); i >= 0 {
+		name, value, op = s[:i], s[i+2:], "!="
+	} else if i := strings.Index(s, "="); i >= 0 {
+		name, value, op = s[:i], s[i+1:], "="
+	}
+	if name == "" {
+		return nil, usageError("invalid filter 'tag:%s': expected tag:NAME or tag:NAME=VALUE", s)
+	}
+
+	return func(ctx *findContext, d types.Device) (bool, error) {
+		tags, e := ctx.deviceTags(d)
+		if e != nil {
+			return false, e
+		}
+		// the API keeps tags among the device's settings as tag_NAME
+		v, present := tags[fmt.Sprintf("tag_%s", name)]
+		switch op {
+		case "=":
+			return present && globMatch(value, string(v)), nil
+		case "!=":
+			return !present || !globMatch(value, string(v)), nil
+		}
+		return present, nil
+	}, nil
+}
+
+// filterParser is a recursive descent parser for filter expressions:
+//
+//	expr := and ("or" and)*
+//	and  := not ("and"? not)*
+//	not  := "not" not | "(" expr ")" | predicate
+//
+// so that predicates next to each other must all match
+type filterParser struct {
+	tokens []findToken
+	pos    int
+}
+
+func (p *filterParser) peek() (findToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return findToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *filterParser) keyword(t findToken, word string) bool {
+	return !t.op && strings.EqualFold(t.text, word)
+}
+
+func (p *filterParser) parseOr() (devicePredicate, error) {
+	left, e := p.parseAnd()
+	if e != nil {
+		return nil, e
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || !p.keyword(t, "or") {
+			return left, nil
+		}
+		p.pos++
+		right, e := p.parseAnd()
+		if e != nil {
+			return nil, e
+		}
+		l := left
+		left = func(ctx *findContext, d types.Device) (bool, error) {
+			if ok, e := l(ctx, d); ok || e != nil {
+				return ok, e
+			}
+			return right(ctx, d)
+		}
+	}
+}
+
+func (p *filterParser) parseAnd() (devicePredicate, error) {
+	left, e := p.parseNot()
+	if e != nil {
+		return nil, e
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || p.keyword(t, "or") || (t.op && t.text == ")") {
+			return left, nil
+		}
+		if p.keyword(t, "and") {
+			p.pos++
+		}
+		right, e := p.parseNot()
+		if e != nil {
+			return nil, e
+		}
+		l := left
+		left = func(ctx *findContext, d types.Device) (bool, error) {
+			if ok, e := l(ctx, d); !ok || e != nil {
+				return false, e
+			}
+			return right(ctx, d)
+		}
+	}
+}
+
+func (p *filterParser) parseNot() (devicePredicate, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, usageError("incomplete filter: expected a condition at the end")
+	}
+	p.pos++
+
+	switch {
+	case p.keyword(t, "not"):
+		inner, e := p.parseNot()
+		if e != nil {
+			return nil, e
+		}
+		return func(ctx *findContext, d types.Device) (bool, error) {
+			ok, e := inner(ctx, d)
+			return !ok, e
+		}, nil
+
+	case t.op && t.text == "(":
+		inner, e := p.parseOr()
+		if e != nil {
+			return nil, e
+		}
+		if t, ok := p.peek(); !ok || !t.op || t.text != ")" {
+			return nil, usageError("missing ')' in filter")
+		}
+		p.pos++
+		return inner, nil
+
+	case t.op && t.text == ")", p.keyword(t, "and"), p.keyword(t, "or"):
+		return nil, usageError("unexpected '%s' in filter", t.text)
+	}
+	return parsePredicate(t.text)
+}
+
+// parseFilter parses a complete filter expression. An empty expression
+// matches every device.
+func parseFilter(expr string) (devicePredicate, error) {
+	tokens, e := lexFilter(expr)
+	if e != nil {
+		return nil, e
+	}
+	if len(tokens) == 0 {
+		return func(*findContext, types.Device) (bool, error) { return true, nil }, nil
+	}
+
+	p := &filterParser{tokens: tokens}
+	filter, e := p.parseOr()
+	if e != nil {
+		return nil, e
+	}
+	if p.pos < len(p.tokens) {
+		return nil, usageError("unexpected '%s' in filter", p.tokens[p.pos].text)
+	}
+	return filter, nil
+}
+
+// splitKeyValue splits a KEY=VALUE option
+func splitKeyValue(option, s string) (string, string, error) {
+	bits := strings.SplitN(s, "=", 2)
+	if len(bits) != 2 || bits[0] == "" {
+		return "", "", usageError("%s must be of the form KEY=VALUE: got '%s'", option, s)
+	}
+	return bits[0], bits[1], nil
+}
+
+// intersectDevices returns the devices in a that are also in b
+func intersectDevices(a, b types.Devices) types.Devices {
+	in := map[types.UUID]bool{}
+	for _, d := range b {
+		in[d.ID] = true
+	}
+	both := types.Devices{}
+	for _, d := range a {
+		if in[d.ID] {
+			both = append(both, d)
+		}
+	}
+	return both
+}
+
+func deviceFindCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Find devices matching a combination of conditions.
+
+The API can't list every device, so at least one of --build, --hostname,
+--setting or --tag picks the devices to start from. Giving more than one
+finds the devices that match all of them.
+
+The optional FILTER then narrows the list down. It is made of conditions
+combined with and, or, not and parentheses; conditions next to each other
+must all match:
+
+  phase=PHASE, health=HEALTH    the device's phase or health
+  build=NAME, rack=NAME         the build or rack, by name or ID
+  hostname=NAME, serial=SERIAL  the hostname or serial number
+  product=NAME                  the hardware product, by name, SKU or ID
+  last_seen<AGE, last_seen>AGE  seen within, or not for, AGE (e.g. 30m, 2h, 7d)
+  tag:NAME                      the device has the tag
+  tag:NAME=VALUE                the tag has the value
+
+Use != for the opposite of =. Values may contain * and ? wildcards and be
+quoted as in the shell. For example:
+
+  kosh devices find --build my-build 'health=fail or (phase=integration and last_seen>1h)'
+  kosh devices find --tag role=storage not tag:reserved`
+
+	var (
+		build     = cmd.StringOpt("build", "", "Start from the devices in this build")
+		hostname  = cmd.StringOpt("hostname", "", "Start from the devices with this hostname")
+		settings  = cmd.StringsOpt("setting", nil, "Start from the devices with this KEY=VALUE setting")
+		tags      = cmd.StringsOpt("tag", nil, "Start from the devices with this KEY=VALUE tag")
+		filterArg = cmd.StringsArg("FILTER", nil, "Conditions the devices must match")
+	)
+	cmd.Spec = "[OPTIONS] [FILTER...]"
+
+	cmd.Action = func() {
+		filter, e := parseFilter(strings.Join(*filterArg, " "))
+		fatalIf(e)
+
+		conch := config.ConchClient()
+		display := config.Renderer()
+
+		var devices types.Devices
+		narrow := func(found types.Devices, e error) {
+			fatalIf(e)
+			if devices == nil {
+				devices = found
+				return
+			}
+			devices = intersectDevices(devices, found)
+		}
+
+		if *build != "" {
+			narrow(conch.GetAllBuildDevices(*build))
+		}
+		if *hostname != "" {
+			narrow(conch.FindDevicesBySetting("hostname", *hostname))
+		}
+		for _, s := range *settings {
+			k, v, e := splitKeyValue("--setting", s)
+			fatalIf(e)
+			narrow(conch.FindDevicesBySetting(k, v))
+		}
+		for _, t := range *tags {
+			k, v, e := splitKeyValue("--tag", t)
+			fatalIf(e)
+			narrow(conch.FindDevicesByTag(k, v))
+		}
+		if devices == nil {
+			fatalIf(usageError("one of --build, --hostname, --setting or --tag is required: the API can't list every device"))
+		}
+
+		ctx := &findContext{
+			now:     time.Now(),
+			getTags: conch.GetDeviceTags,
+			getProduct: func(name string) (types.UUID, error) {
+				p, e := conch.GetHardwareProductByID(name)
+				if e != nil {
+					return types.UUID{}, fmt.Errorf("hardware product '%s': %w", name, e)
+				}
+				return p.ID, nil
+			},
+		}
+
+		found := types.Devices{}
+		for _, d := range devices {
+			ok, e := filter(ctx, d)
+			fatalIf(e)
+			if ok {
+				found = append(found, d)
+			}
+		}
+		display(found, nil)
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexFilter(t *testing.T) {
+	tokens, e := lexFilter(`(phase=production or not tag:x)and hostname="a (b)"`)
+	assert.Nil(t, e)
+	assert.Equal(t, []findToken{
+		{text: "(", op: true},
+		{text: "phase=production"},
+		{text: "or"},
+		{text: "not"},
+		{text: "tag:x"},
+		{text: ")", op: true},
+		{text: "and"},
+		{text: "hostname=a (b)"},
+	}, tokens)
+
+	_, e = lexFilter(`hostname="a`)
+	assert.Equal(t, ExitUsage, exitCode(e))
+}
+
+func TestDeviceFilter(t *testing.T) {
+	now := time.Now()
+	product := types.UUID{UUID: uuid.Must(uuid.NewV4())}
+
+	devices := types.Devices{
+		{
+			ID:                types.UUID{UUID: uuid.Must(uuid.NewV4())},
+			SerialNumber:      "S1",
+			Hostname:          "web-1",
+			Phase:             "integration",
+			Health:            "fail",
+			BuildName:         "b1",
+			RackName:          "r1",
+			HardwareProductID: product,
+			LastSeen:          now.Add(-2 * time.Hour),
+		},
+		{
+			ID:           types.UUID{UUID: uuid.Must(uuid.NewV4())},
+			SerialNumber: "S2",
+			Hostname:     "db-1",
+			Phase:        "production",
+			Health:       "pass",
+			BuildName:    "b1",
+			LastSeen:     now.Add(-time.Minute),
+		},
+		{
+			ID:           types.UUID{UUID: uuid.Must(uuid.NewV4())},
+			SerialNumber: "S3",
+			Phase:        "production",
+			Health:       "unknown",
+		},
+	}
+	tags := map[string]types.DeviceSettings{
+		devices[0].ID.String(): {"tag_role": "web", "hostname": "x"},
+		devices[1].ID.String(): {"tag_role": "db", "tag_reserved": "yes"},
+		devices[2].ID.String(): {"reserved": "yes", "role": "web"},
+	}
+
+	tagFetches := 0
+	ctx := &findContext{
+		now: now,
+		getTags: func(id string) (types.DeviceSettings, error) {
+			tagFetches++
+			return tags[id], nil
+		},
+		getProduct: func(name string) (types.UUID, error) {
+			if name == "my-sku" {
+				return product, nil
+			}
+			return types.UUID{}, errors.New("not found")
+		},
+	}
+
+	tests := map[string][]string{
+		"":                                 {"S1", "S2", "S3"},
+		"phase=production":                 {"S2", "S3"},
+		"phase=production health=pass":     {"S2"},
+		"health=fail or health=pass":       {"S1", "S2"},
+		"not (health=fail or health=pass)": {"S3"},
+		"phase!=production":                {"S1"},
+		"hostname='web-*'":                 {"S1"},
+		"rack=*":                           {"S1"},
+		"build=b1 and last_seen<1h":        {"S2"},
+		"last_seen>1h":                     {"S1", "S3"},
+		"last_seen>1d":                     {"S3"},
+		"tag:reserved":                     {"S2"},
+		"not tag:reserved":                 {"S1", "S3"},
+		"tag:role=w*":                      {"S1"},
+		"tag:role!=web":                    {"S2", "S3"},
+		"tag:hostname":                     {},
+		"product=my-sku":                   {"S1"},
+		"product!=" + product.String():     {"S2", "S3"},
+		"PHASE=production AND NOT health=unknown": {"S2"},
+	}
+	for expr, want := range tests {
+		filter, e := parseFilter(expr)
+		if !assert.Nil(t, e, expr) {
+			continue
+		}
+		got := []string{}
+		for _, d := range devices {
+			ok, e := filter(ctx, d)
+			assert.Nil(t, e, expr)
+			if ok {
+				got = append(got, string(d.SerialNumber))
+			}
+		}
+		assert.Equal(t, want, got, expr)
+	}
+	assert.Equal(t, 3, tagFetches, "tags are only fetched once per device")
+
+	filter, e := parseFilter("product=missing")
+	assert.Nil(t, e)
+	_, e = filter(ctx, devices[0])
+	assert.NotNil(t, e)
+
+	for _, bad := range []string{
+		"phase",
+		"colour=red",
+		"last_seen=1h",
+		"last_seen>soon",
+		"phase=x or",
+		"(phase=x",
+		"phase=x)",
+		"and phase=x",
+		"tag:",
+	} {
+		_, e := parseFilter(bad)
+		assert.Equal(t, ExitUsage, exitCode(e), bad)
+	}
+}
+
+func TestIntersectDevices(t *testing.T) {
+	a := types.UUID{UUID: uuid.Must(uuid.NewV4())}
+	b := types.UUID{UUID: uuid.Must(uuid.NewV4())}
+	both := intersectDevices(types.Devices{{ID: a}, {ID: b}}, types.Devices{{ID: b}})
+	assert.Equal(t, types.Devices{{ID: b}}, both)
+}
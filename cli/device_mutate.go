@@ -0,0 +1,336 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// deviceChange is a single field of a device that a change altered
+type deviceChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type deviceChanges []deviceChange
+
+func (d deviceChanges) Len() int           { return len(d) }
+func (d deviceChanges) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d deviceChanges) Less(i, j int) bool { return d[i].Field < d[j].Field }
+
+// Headers returns the list of headers for the table view
+func (d deviceChanges) Headers() []string {
+	return []string{
+		"Field",
+		"Before",
+		"After",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (d deviceChanges) ForEach(do func([]string)) {
+	for _, c := range d {
+		do([]string{c.Field, c.Before, c.After})
+	}
+}
+
+// ignoredDeviceFields are left out of the diff: the latest report is too big
+// to compare usefully and updated changes with every change
+var ignoredDeviceFields = map[string]bool{
+	"latest_report": true,
+	"updated":       true,
+}
+
+// flattenJSON walks a decoded JSON value, adding every scalar in it to fields
+// under its path, e.g. location.rack or links[0]
+func flattenJSON(prefix string, v interface{}, fields map[string]string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			flattenJSON(path, child, fields)
+		}
+	case []interface{}:
+		for i, child := range t {
+			flattenJSON(fmt.Sprintf("%s[%d]", prefix, i), child, fields)
+		}
+	case nil:
+		fields[prefix] = ""
+	case string:
+		fields[prefix] = t
+	default:
+		fields[prefix] = renderJSON(t)
+	}
+}
+
+// flattenDevice returns every field of a device by its path
+func flattenDevice(d types.DetailedDevice) (map[string]string, error) {
+	b, e := json.Marshal(d)
+	if e != nil {
+		return nil, e
+	}
+	var decoded map[string]interface{}
+	if e := json.Unmarshal(b, &decoded); e != nil {
+		return nil, e
+	}
+	for k := range ignoredDeviceFields {
+		delete(decoded, k)
+	}
+	fields := map[string]string{}
+	flattenJSON("", decoded, fields)
+	return fields, nil
+}
+
+// diffDevices returns the fields that differ between two versions of a
+// device, sorted by field
+func diffDevices(before, after types.DetailedDevice) (deviceChanges, error) {
+	was, e := flattenDevice(before)
+	if e != nil {
+		return nil, e
+	}
+	now, e := flattenDevice(after)
+	if e != nil {
+		return nil, e
+	}
+	return diffFlattened(was, now), nil
+}
+
+// diffSettings returns the settings that differ between two versions of a
+// device's settings, sorted by name
+func diffSettings(before, after types.DeviceSettings) deviceChanges {
+	was := map[string]string{}
+	for k, v := range before {
+		was[k] = string(v)
+	}
+	now := map[string]string{}
+	for k, v := range after {
+		now[k] = string(v)
+	}
+	return diffFlattened(was, now)
+}
+
+// diffFlattened returns the fields that differ between two sets of fields,
+// sorted by field
+func diffFlattened(was, now map[string]string) deviceChanges {
+	changes := deviceChanges{}
+	for k, v := range was {
+		if w, ok := now[k]; !ok || v != w {
+			changes = append(changes, deviceChange{Field: k, Before: v, After: w})
+		}
+	}
+	for k, w := range now {
+		if _, ok := was[k]; !ok {
+			changes = append(changes, deviceChange{Field: k, After: w})
+		}
+	}
+	sort.Sort(changes)
+	return changes
+}
+
+// validateAssetTag checks an asset tag is one the API will accept: not empty
+// and without whitespace
+func validateAssetTag(tag string) error {
+	if tag == "" {
+		return validationError("asset tag must not be empty")
+	}
+	if strings.IndexFunc(tag, unicode.IsSpace) >= 0 {
+		return validationError("asset tag must not contain whitespace: got '%s'", tag)
+	}
+	return nil
+}
+
+// parseLinks checks every link is an absolute URL
+func parseLinks(uris []string) (types.DeviceLinks, error) {
+	for _, u := range uris {
+		parsed, e := url.Parse(u)
+		if e != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return types.DeviceLinks{}, validationError("link must be an absolute URL: got '%s'", u)
+		}
+	}
+	return types.NewDeviceLinks(uris...), nil
+}
+
+// parseRackUnit parses the rack unit a device starts at, counting from 1
+func parseRackUnit(ru string) (int, error) {
+	n, e := strconv.Atoi(ru)
+	if e != nil || n < 1 {
+		return 0, validationError("rack unit must be a positive integer: got '%s'", ru)
+	}
+	return n, nil
+}
+
+// parseSettings parses KEY=VALUE pairs into device settings
+func parseSettings(pairs []string) (types.DeviceSettings, error) {
+	settings := types.DeviceSettings{}
+	for _, p := range pairs {
+		bits := strings.SplitN(p, "=", 2)
+		if len(bits) != 2 || bits[0] == "" {
+			return nil, validationError("setting must be of the form KEY=VALUE: got '%s'", p)
+		}
+		settings[bits[0]] = types.DeviceSetting(bits[1])
+	}
+	return settings, nil
+}
+
+// mutateDevice makes a change to a device and renders what it changed
+func mutateDevice(id string, change func(c *conch.Client) error) {
+	client := config.ConchClient()
+
+	before, e := client.GetDeviceBySerial(id)
+	fatalIf(e)
+	fatalIf(change(client))
+	after, e := client.GetDeviceBySerial(id)
+	fatalIf(e)
+
+	changes, e := diffDevices(before, after)
+	fatalIf(e)
+	renderChanges(changes)
+}
+
+// mutateDeviceSettings makes a change to a device's settings and renders the
+// settings it changed
+func mutateDeviceSettings(id string, change func(c *conch.Client) error) {
+	client := config.ConchClient()
+
+	before, e := client.GetDeviceSettings(id)
+	fatalIf(e)
+	fatalIf(change(client))
+	after, e := client.GetDeviceSettings(id)
+	fatalIf(e)
+
+	renderChanges(diffSettings(before, after))
+}
+
+func renderChanges(changes deviceChanges) {
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stderr, "No changes")
+	}
+	config.Renderer()(changes, nil)
+}
+
+func deviceAssetTagCmd(id *string) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		cmd.Command("set", "Set the asset tag of the device", func(cmd *cli.Cmd) {
+			tag := cmd.StringArg("TAG", "", "The asset tag, without whitespace")
+			cmd.Spec = "TAG"
+			cmd.Action = func() {
+				fatalIf(validateAssetTag(*tag))
+				mutateDevice(*id, func(c *conch.Client) error {
+					return c.SetDeviceAssetTag(*id, types.DeviceAssetTag(*tag))
+				})
+			}
+		})
+	}
+}
+
+func deviceLinksCmd(id *string) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		cmd.Command("set", "Add links to the device", func(cmd *cli.Cmd) {
+			uris := cmd.StringsArg("URL", nil, "Absolute URLs to link to the device")
+			cmd.Spec = "URL..."
+			cmd.Action = func() {
+				links, e := parseLinks(*uris)
+				fatalIf(e)
+				mutateDevice(*id, func(c *conch.Client) error {
+					return c.SetDeviceLinks(*id, links)
+				})
+			}
+		})
+
+		cmd.Command("delete rm", "Remove every link from the device", func(cmd *cli.Cmd) {
+			cmd.Action = func() {
+				mutateDevice(*id, func(c *conch.Client) error {
+					return c.DeleteDeviceLinks(*id)
+				})
+			}
+		})
+	}
+}
+
+func deviceBuildCmd(id *string) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		cmd.Command("set", "Move the device to a build", func(cmd *cli.Cmd) {
+			name := cmd.StringArg("BUILD", "", "Name or ID of the build")
+			cmd.Spec = "BUILD"
+			cmd.Action = func() {
+				mutateDevice(*id, func(c *conch.Client) error {
+					build, e := c.GetBuildByName(*name)
+					if e != nil {
+						return e
+					}
+					return c.SetDeviceBuild(*id, map[string]types.UUID{"build_id": build.ID})
+				})
+			}
+		})
+	}
+}
+
+func deviceSKUCmd(id *string) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		cmd.Command("set", "Set the hardware product of the device", func(cmd *cli.Cmd) {
+			product := cmd.StringArg("PRODUCT", "", "SKU, name, alias or ID of the hardware product")
+			cmd.Spec = "PRODUCT"
+			cmd.Action = func() {
+				mutateDevice(*id, func(c *conch.Client) error {
+					hw, e := c.GetHardwareProductByID(*product)
+					if e != nil {
+						return e
+					}
+					return c.SetDeviceSKU(*id, map[string]string{"sku": string(hw.SKU)})
+				})
+			}
+		})
+	}
+}
+
+func deviceLocationCmd(id *string) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		cmd.Command("get", "Get the location of the device", func(cmd *cli.Cmd) {
+			cmd.Action = func() {
+				display := config.Renderer()
+				display(config.ConchClient().GetDeviceLocation(*id))
+			}
+		})
+
+		cmd.Command("set", "Place the device in a rack", func(cmd *cli.Cmd) {
+			rackName := cmd.StringArg("RACK", "", "Name or ID of the rack")
+			ru := cmd.StringArg("RU", "", "Rack unit the device starts at")
+			cmd.Spec = "RACK RU"
+			cmd.Action = func() {
+				start, e := parseRackUnit(*ru)
+				fatalIf(e)
+				mutateDevice(*id, func(c *conch.Client) error {
+					rack, e := c.GetRackByName(*rackName)
+					if e != nil {
+						return e
+					}
+					return c.SetDeviceLocation(*id, map[string]interface{}{
+						"rack_id":         rack.ID,
+						"rack_unit_start": start,
+					})
+				})
+			}
+		})
+
+		cmd.Command("delete rm", "Remove the device from its rack", func(cmd *cli.Cmd) {
+			cmd.Action = func() {
+				mutateDevice(*id, func(c *conch.Client) error {
+					return c.DeleteDeviceLocation(*id)
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDevices(t *testing.T) {
+	before := types.DetailedDevice{
+		AssetTag: "A1",
+		Links:    []types.DetailedDeviceLink{"https://example.com/1"},
+		Location: types.DeviceLocation{Rack: "R1", RackUnitStart: 3},
+	}
+	after := before
+	after.AssetTag = "A2"
+	after.Links = []types.DetailedDeviceLink{"https://example.com/1", "https://example.com/2"}
+	after.Location.RackUnitStart = 5
+	after.LatestReport.BiosVersion = "ignored"
+
+	changes, e := diffDevices(before, after)
+	assert.Nil(t, e)
+	assert.Equal(t, deviceChanges{
+		{Field: "asset_tag", Before: "A1", After: "A2"},
+		{Field: "links[1]", After: "https://example.com/2"},
+		{Field: "location.rack_unit_start", Before: "3", After: "5"},
+	}, changes)
+
+	changes, e = diffDevices(before, before)
+	assert.Nil(t, e)
+	assert.Empty(t, changes)
+}
+
+func TestDiffSettings(t *testing.T) {
+	before := types.DeviceSettings{"firmware": "old", "tag_role": "web"}
+	after := types.DeviceSettings{"firmware": "current", "tag_role": "web", "build.channel": "stable"}
+
+	assert.Equal(t, deviceChanges{
+		{Field: "build.channel", After: "stable"},
+		{Field: "firmware", Before: "old", After: "current"},
+	}, diffSettings(before, after))
+	assert.Empty(t, diffSettings(before, before))
+}
+
+func TestMutationValidation(t *testing.T) {
+	assert.Nil(t, validateAssetTag("ABC-123"))
+	assert.Equal(t, ExitValidation, exitCode(validateAssetTag("")))
+	assert.Equal(t, ExitValidation, exitCode(validateAssetTag("ABC 123")))
+
+	links, e := parseLinks([]string{"https://example.com/ticket/1"})
+	assert.Nil(t, e)
+	assert.Equal(t, types.NewDeviceLinks("https://example.com/ticket/1"), links)
+	_, e = parseLinks([]string{"https://example.com", "ticket/1"})
+	assert.Equal(t, ExitValidation, exitCode(e))
+
+	ru, e := parseRackUnit("12")
+	assert.Nil(t, e)
+	assert.Equal(t, 12, ru)
+	for _, bad := range []string{"0", "-1", "top"} {
+		_, e = parseRackUnit(bad)
+		assert.Equal(t, ExitValidation, exitCode(e), bad)
+	}
+
+	settings, e := parseSettings([]string{"a=1", "b=x=y", "c="})
+	assert.Nil(t, e)
+	assert.Equal(t, types.DeviceSettings{"a": "1", "b": "x=y", "c": ""}, settings)
+	_, e = parseSettings([]string{"novalue"})
+	assert.Equal(t, ExitValidation, exitCode(e))
+}
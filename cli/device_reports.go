@@ -1,20 +1,217 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
 	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/collect"
 	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/joyent/kosh/spool"
 )
 
 func deviceReportCmd(cmd *cli.Cmd) {
 	cmd.Command("post", "Post a new device report", func(cmd *cli.Cmd) {
 		var conch *conch.Client
 
-		filePathArg := cmd.StringArg("FILE", "-", "Path to a JSON file that defines the layout. '-' indicates STDIN")
-
-		input, err := getInputReader(*filePathArg)
-		fatalIf(err)
+		filePathArg := cmd.StringArg("FILE", "-", "Path to a JSON file that defines the device report. '-' indicates STDIN")
+		spoolDir := cmd.StringOpt("spool", "", "If the report can't be sent, keep it in this directory to send later with flush")
+		cmd.Spec = "[OPTIONS] [FILE] [OPTIONS]"
 
 		cmd.Before = func() { conch = config.ConchClient() }
-		cmd.Action = func() { conch.SendDeviceReport(input) }
+		cmd.Action = func() {
+			input, err := getInputReader(*filePathArg)
+			fatalIf(err)
+			report, err := ioutil.ReadAll(input)
+			fatalIf(err)
+			if e := json.Unmarshal(report, &types.DeviceReport{}); e != nil {
+				fatalIf(validationError("unable to read the device report in %s: %s", *filePathArg, e))
+			}
+
+			err = conch.SendDeviceReport(bytes.NewReader(report))
+			if *spoolDir == "" || err == nil || !spool.Retryable(err) {
+				fatalIf(err)
+				return
+			}
+			entry := spoolReport(*spoolDir, report)
+			config.Warn(fmt.Sprintf("unable to send the device report (%s), spooled it to %s", err, entry.Path))
+		}
 	})
+
+	cmd.Command("flush", "Send the device reports spooled by post --spool", reportFlushCmd)
+	cmd.Command("validate", "Check a device report against the schema and the validations, without recording it", reportValidateCmd)
+	cmd.Command("diff", "Show what changed between two device report files", reportDiffCmd)
+	cmd.Command("collect", "Collect a device report from this Linux host", reportCollectCmd)
+	cmd.Command("scrub", "Replace the identifiers in device reports so that they can be shared", reportScrubCmd)
+}
+
+func reportCollectCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Collect a device report from this Linux host and print it, or post it with --post.
+
+The report is read from sysfs, procfs and the DMI tables: the serial number,
+system UUID, product name, BIOS version, CPUs, DIMMs, disks, network
+interfaces, hostname and uptime. Reading the serial number usually needs root.
+
+--root reads those trees from under another directory instead of /, e.g. a
+copy of /sys and /proc captured from another host.`
+
+	root := cmd.StringOpt("root", "/", "Directory to read /sys and /proc from")
+	post := cmd.BoolOpt("post", false, "Post the report to the API instead of printing it")
+
+	cmd.Action = func() {
+		report, e := collect.New(*root).Report()
+		fatalIf(e)
+
+		b, e := json.MarshalIndent(report, "", "  ")
+		fatalIf(e)
+		if !*post {
+			fmt.Println(string(formatJSON(b, config.OutputJSON)))
+			return
+		}
+		fatalIf(config.ConchClient().SendDeviceReport(bytes.NewReader(b)))
+		config.Info("posted the device report for", report.SerialNumber)
+	}
+}
+
+// spoolReport keeps a report that couldn't be sent in the spool directory
+func spoolReport(dir string, report []byte) spool.Entry {
+	s, e := spool.New(dir)
+	fatalIf(e)
+	entry, e := s.Add(report)
+	fatalIf(e)
+	return entry
+}
+
+type spoolResults []spool.Result
+
+func (s spoolResults) Len() int           { return len(s) }
+func (s spoolResults) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s spoolResults) Less(i, j int) bool { return s[i].Spooled.Before(s[j].Spooled) }
+
+// Headers returns the list of headers for the table view
+func (s spoolResults) Headers() []string {
+	return []string{
+		"Serial",
+		"Timestamp",
+		"Spooled",
+		"Status",
+		"Attempts",
+		"Error",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (s spoolResults) ForEach(do func([]string)) {
+	for _, r := range s {
+		message := ""
+		if r.Error != nil {
+			message = r.Error.Error()
+		}
+		timestamp := ""
+		if !r.Timestamp.IsZero() {
+			timestamp = r.Timestamp.Format(time.RFC3339)
+		}
+		do([]string{
+			r.Serial,
+			timestamp,
+			r.Spooled.Format(time.RFC3339),
+			r.Status,
+			fmt.Sprintf("%d", r.Attempts),
+			message,
+		})
+	}
+}
+
+// MarshalJSON renders the results with their errors as strings
+func (s spoolResults) MarshalJSON() ([]byte, error) {
+	type result struct {
+		Serial    string     `json:"serial"`
+		Timestamp *time.Time `json:"timestamp,omitempty"`
+		Digest    string     `json:"digest"`
+		Spooled   time.Time  `json:"spooled"`
+		Path      string     `json:"path"`
+		Status    string     `json:"status"`
+		Attempts  int        `json:"attempts"`
+		Error     string     `json:"error,omitempty"`
+	}
+	results := []result{}
+	for _, r := range s {
+		out := result{
+			Serial:   r.Serial,
+			Digest:   r.Digest,
+			Spooled:  r.Spooled,
+			Path:     r.Path,
+			Status:   r.Status,
+			Attempts: r.Attempts,
+		}
+		if !r.Timestamp.IsZero() {
+			timestamp := r.Timestamp
+			out.Timestamp = &timestamp
+		}
+		if r.Error != nil {
+			out.Error = r.Error.Error()
+		}
+		results = append(results, out)
+	}
+	return json.Marshal(results)
+}
+
+func reportFlushCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Send the device reports spooled by post --spool, oldest first.
+
+Each report is tried --attempts times, waiting --backoff and then twice as
+long each time. Delivered reports are removed from the spool, as are
+duplicates: reports with the same serial number and timestamp (uptime_since)
+as one already sent, or the same content if they have no timestamp. Reports
+the API rejects outright, or that aren't valid JSON, are moved to the
+rejected directory in the spool. If a report still can't be sent, flushing
+stops so that the reports are delivered in order, and the rest are left for
+the next flush.
+
+The exit status is non zero if any report was rejected or is still pending.`
+
+	spoolDir := cmd.StringOpt("spool", "", "Directory the reports were spooled to")
+	attempts := cmd.IntOpt("attempts", spool.DefaultBackoff.Attempts, "Number of times to try each report")
+	backoff := cmd.StringOpt("backoff", spool.DefaultBackoff.Initial.String(), "How long to wait after the first failed attempt")
+	cmd.Spec = "--spool [OPTIONS]"
+
+	cmd.Action = func() {
+		initial, e := time.ParseDuration(*backoff)
+		if e != nil {
+			fatalIf(usageError("--backoff must be a duration like 1s or 500ms: got '%s'", *backoff))
+		}
+		if *attempts < 1 {
+			fatalIf(usageError("--attempts must be at least 1"))
+		}
+
+		s, e := spool.New(*spoolDir)
+		fatalIf(e)
+		client := config.ConchClient()
+		results, e := s.Flush(
+			spool.Backoff{Attempts: *attempts, Initial: initial, Max: spool.DefaultBackoff.Max},
+			func(report []byte) error { return client.SendDeviceReport(bytes.NewReader(report)) },
+		)
+		fatalIf(e)
+
+		if len(results) == 0 {
+			fmt.Fprintln(os.Stderr, "No spooled reports")
+			return
+		}
+		config.Renderer()(spoolResults(results), nil)
+
+		failed := 0
+		for _, r := range results {
+			if r.Status == spool.Rejected || r.Status == spool.Pending {
+				failed++
+			}
+		}
+		if failed > 0 {
+			fatalIf(fmt.Errorf("%d of %d spooled reports were not delivered", failed, len(results)))
+		}
+	}
 }
@@ -2,7 +2,6 @@ package cli
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"strings"
 
@@ -13,6 +12,8 @@ import (
 func devicesCmd(cmd *cli.Cmd) {
 	cmd.Before = config.requireAuth
 	cmd.Command("search s", "Search for devices", deviceSearchCmd)
+	cmd.Command("find f", "Find devices matching a combination of conditions", deviceFindCmd)
+	cmd.Command("bulk", "Make the same kind of change to many devices", deviceBulkCmd)
 }
 
 func deviceSearchCmd(cmd *cli.Cmd) {
@@ -79,6 +80,11 @@ func deviceCmd(cmd *cli.Cmd) {
 	cmd.Command("preflight", "Data that is only accurate inside preflight", devicePreflightCmd(id))
 	cmd.Command("phase", "Actions on the lifecycle phase of the device", devicePhaseCmd(id))
 	cmd.Command("report", "Get the most recently recorded report for this device", deviceDeviceReportCmd(id))
+	cmd.Command("asset-tag", "Change the asset tag of the device", deviceAssetTagCmd(id))
+	cmd.Command("links", "Change the links of the device", deviceLinksCmd(id))
+	cmd.Command("build", "Change the build of the device", deviceBuildCmd(id))
+	cmd.Command("sku", "Change the hardware product of the device", deviceSKUCmd(id))
+	cmd.Command("location", "Change where the device is racked", deviceLocationCmd(id))
 }
 
 func deviceGetCmd(id *string) func(cmd *cli.Cmd) {
@@ -111,6 +117,18 @@ func deviceSettingsCmd(id *string) func(cmd *cli.Cmd) {
 
 			display(conch.GetDeviceSettings(*id))
 		}
+
+		cmd.Command("set", "Set several settings at once", func(cmd *cli.Cmd) {
+			pairs := cmd.StringsArg("SETTING", nil, "Settings to set, as KEY=VALUE")
+			cmd.Spec = "SETTING..."
+			cmd.Action = func() {
+				settings, e := parseSettings(*pairs)
+				fatalIf(e)
+				mutateDeviceSettings(*id, func(c *conch.Client) error {
+					return c.SetDeviceSettings(*id, settings)
+				})
+			}
+		})
 	}
 }
 
@@ -143,14 +161,14 @@ func deviceSettingCmd(id *string) func(cmd *cli.Cmd) {
 			cmd.Spec = "VALUE"
 
 			cmd.Action = func() {
-				conch.SetDeviceSetting(*id, key, value)
+				fatalIf(conch.SetDeviceSetting(*id, key, value))
 				display(conch.GetDeviceSettings(*id))
 			}
 		})
 
 		cmd.Command("delete rm", "Delete a particular device setting", func(cmd *cli.Cmd) {
 			cmd.Action = func() {
-				conch.DeleteDeviceSetting(*id, key)
+				fatalIf(conch.DeleteDeviceSetting(*id, key))
 				display(conch.GetDeviceSettings(*id))
 			}
 		})
@@ -192,14 +210,14 @@ func deviceTagCmd(id *string) func(cmd *cli.Cmd) {
 			cmd.Spec = "VALUE"
 
 			cmd.Action = func() {
-				conch.SetDeviceTag(*id, name, value)
+				fatalIf(conch.SetDeviceTag(*id, name, value))
 				display(conch.GetDeviceTags(*id))
 			}
 		})
 
 		cmd.Command("delete rm", "Delete a particular device tag", func(cmd *cli.Cmd) {
 			cmd.Action = func() {
-				conch.DeleteDeviceTag(*id, name)
+				fatalIf(conch.DeleteDeviceTag(*id, name))
 				display(conch.GetDeviceTags(*id))
 			}
 		})
@@ -290,9 +308,9 @@ func devicePhaseCmd(id *string) func(cmd *cli.Cmd) {
 			cmd.Spec = "PHASE"
 			cmd.Action = func() {
 				if !okPhase(phase) {
-					log.Fatal("Phase must be one of: " + prettyPhasesList())
+					fatalIf(validationError("Phase must be one of: %s", prettyPhasesList()))
 				}
-				conch.SetDevicePhase(*id, phase)
+				fatalIf(conch.SetDevicePhase(*id, phase))
 				display(conch.GetDevicePhase(*id))
 			}
 		})
@@ -307,5 +325,7 @@ func deviceDeviceReportCmd(id *string) func(cmd *cli.Cmd) {
 			d, e := conch.GetDeviceBySerial(*id)
 			display(d.LatestReport, e)
 		}
+
+		cmd.Command("diff", "Show what changed between two reports for this device", deviceReportDiffCmd(id))
 	}
 }
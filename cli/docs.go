@@ -0,0 +1,320 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+	"unsafe"
+
+	cli "github.com/jawher/mow.cli"
+)
+
+// docsCommand is the name of the hidden command that generates the command
+// reference. It is handled before mow.cli sees the arguments so that it
+// doesn't appear in the help output.
+const docsCommand = "docs"
+
+// docItem is a single argument, option or subcommand in a help page
+type docItem struct {
+	Name    string
+	Desc    string
+	Env     []string
+	Default string
+}
+
+// docPage is the reference page of a single command
+type docPage struct {
+	// Path is the command path, e.g. ["kosh", "device", "get"]
+	Path        []string
+	Usage       string
+	Description string
+	Arguments   []docItem
+	Options     []docItem
+	Commands    []docItem
+	Children    []*docPage
+}
+
+// Name returns the command path joined with spaces, as it's typed
+func (p *docPage) Name() string { return strings.Join(p.Path, " ") }
+
+// FileName returns the base name of the page's file, without an extension
+func (p *docPage) FileName() string { return strings.Join(p.Path, "_") }
+
+// field returns the named field of v, which must be addressable, even if it
+// is unexported. mow.cli has no accessors for its command tree.
+func field(v reflect.Value, name string) reflect.Value {
+	f := v.FieldByName(name)
+	return reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+}
+
+// docItems converts mow.cli's option or argument containers to docItems,
+// formatted the way --help shows them
+func docItems(list reflect.Value) []docItem {
+	items := []docItem{}
+	for i := 0; i < list.Len(); i++ {
+		c := list.Index(i).Elem()
+
+		item := docItem{
+			Name: c.FieldByName("Name").String(),
+			Desc: c.FieldByName("Desc").String(),
+			Env:  strings.Fields(c.FieldByName("EnvVar").String()),
+		}
+
+		short, long := "", ""
+		for _, n := range c.FieldByName("Names").Interface().([]string) {
+			if len(n) == 2 && short == "" {
+				short = n
+			}
+			if len(n) > 2 && long == "" {
+				long = n
+			}
+		}
+		switch {
+		case short != "" && long != "":
+			item.Name = short + ", " + long
+		case short != "":
+			item.Name = short
+		case long != "":
+			item.Name = long
+		}
+
+		if !c.FieldByName("HideValue").Bool() {
+			v := c.FieldByName("Value").Interface().(flag.Value)
+			if d, ok := v.(interface{ IsDefault() bool }); !ok || !d.IsDefault() {
+				item.Default = v.String()
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// docTree builds the docPage of cmd, found at path, and of each of its
+// subcommands
+func docTree(cmd *cli.Cmd, path []string) *docPage {
+	v := reflect.ValueOf(cmd).Elem()
+
+	// mow.cli only runs the initializer of a command that was picked on
+	// the command line
+	if init := field(v, "init"); !init.IsNil() {
+		init.Interface().(cli.CmdInitializer)(cmd)
+		init.Set(reflect.Zero(init.Type()))
+	}
+
+	page := &docPage{
+		Path:        path,
+		Description: strings.TrimSpace(cmd.LongDesc),
+		Arguments:   docItems(field(v, "args")),
+		Options:     docItems(field(v, "options")),
+	}
+	if page.Description == "" {
+		page.Description = strings.TrimSpace(field(v, "desc").String())
+	}
+
+	// without a spec, mow.cli makes one from the options and arguments
+	usage := append([]string{}, path...)
+	if spec := strings.TrimSpace(cmd.Spec); spec != "" {
+		usage = append(usage, spec)
+	} else {
+		if len(page.Options) > 0 {
+			usage = append(usage, "[OPTIONS]")
+		}
+		for _, a := range page.Arguments {
+			usage = append(usage, a.Name)
+		}
+	}
+
+	commands := field(v, "commands")
+	if commands.Len() > 0 {
+		usage = append(usage, "COMMAND [arg...]")
+	}
+	page.Usage = strings.Join(usage, " ")
+
+	for i := 0; i < commands.Len(); i++ {
+		sub := commands.Index(i).Interface().(*cli.Cmd)
+		sv := reflect.ValueOf(sub).Elem()
+		page.Commands = append(page.Commands, docItem{
+			Name: strings.Join(field(sv, "aliases").Interface().([]string), ", "),
+			Desc: field(sv, "desc").String(),
+		})
+		name := field(sv, "name").String()
+		page.Children = append(page.Children, docTree(sub, append(append([]string{}, path...), name)))
+	}
+	return page
+}
+
+// flatten returns the page and all its descendants, depth first
+func (p *docPage) flatten() []*docPage {
+	pages := []*docPage{p}
+	for _, c := range p.Children {
+		pages = append(pages, c.flatten()...)
+	}
+	return pages
+}
+
+func renderMarkdown(p *docPage) string {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "# %s\n\n", p.Name())
+	fmt.Fprintf(b, "```\n%s\n```\n\n", p.Usage)
+	if p.Description != "" {
+		fmt.Fprintf(b, "%s\n\n", p.Description)
+	}
+
+	items := func(title string, list []docItem) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(b, "## %s\n\n", title)
+		for _, i := range list {
+			fmt.Fprintf(b, "* `%s` - %s", i.Name, i.Desc)
+			if len(i.Env) > 0 {
+				fmt.Fprintf(b, " Environment: `%s`.", strings.Join(i.Env, "`, `"))
+			}
+			if i.Default != "" {
+				fmt.Fprintf(b, " Default: `%s`.", strings.Trim(i.Default, `"`))
+			}
+			fmt.Fprintln(b)
+		}
+		fmt.Fprintln(b)
+	}
+	items("Arguments", p.Arguments)
+	items("Options", p.Options)
+
+	if len(p.Children) > 0 {
+		fmt.Fprintf(b, "## Commands\n\n")
+		for i, c := range p.Children {
+			fmt.Fprintf(b, "* [%s](%s.md) - %s\n", p.Commands[i].Name, c.FileName(), p.Commands[i].Desc)
+		}
+		fmt.Fprintln(b)
+	}
+
+	if len(p.Path) > 1 {
+		parent := &docPage{Path: p.Path[:len(p.Path)-1]}
+		fmt.Fprintf(b, "See also [%s](%s.md)\n", parent.Name(), parent.FileName())
+	}
+	return b.String()
+}
+
+// roff escapes text for use in a man page
+func roff(s string) string {
+	s = strings.Replace(s, `\`, `\e`, -1)
+	s = strings.Replace(s, "-", `\-`, -1)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = `\&` + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderMan(p *docPage, version string, date time.Time) string {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(
+		b,
+		".TH %s 1 \"%s\" \"kosh %s\" \"Kosh Manual\"\n",
+		strings.ToUpper(p.FileName()),
+		date.Format("January 2006"),
+		version,
+	)
+	fmt.Fprintf(b, ".SH NAME\n%s", roff(p.FileName()))
+	if p.Description != "" {
+		fmt.Fprintf(b, " \\- %s", roff(strings.SplitN(p.Description, "\n", 2)[0]))
+	}
+	fmt.Fprintf(b, "\n.SH SYNOPSIS\n.B %s\n", roff(p.Usage))
+	if p.Description != "" {
+		fmt.Fprintf(b, ".SH DESCRIPTION\n.nf\n%s\n.fi\n", roff(p.Description))
+	}
+
+	items := func(title string, list []docItem) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(b, ".SH %s\n", title)
+		for _, i := range list {
+			fmt.Fprintf(b, ".TP\n.B %s\n%s\n", roff(i.Name), roff(i.Desc))
+			if i.Default != "" {
+				fmt.Fprintf(b, "Default: %s.\n", roff(i.Default))
+			}
+		}
+	}
+	items("ARGUMENTS", p.Arguments)
+	items("OPTIONS", p.Options)
+	items("COMMANDS", p.Commands)
+
+	env := []docItem{}
+	for _, o := range p.Options {
+		for _, e := range o.Env {
+			env = append(env, docItem{Name: e, Desc: "Sets " + o.Name + "."})
+		}
+	}
+	items("ENVIRONMENT", env)
+
+	also := []string{}
+	if len(p.Path) > 1 {
+		also = append(also, strings.Join(p.Path[:len(p.Path)-1], "_"))
+	}
+	for _, c := range p.Children {
+		also = append(also, c.FileName())
+	}
+	if len(also) > 0 {
+		for i, a := range also {
+			also[i] = fmt.Sprintf(".BR %s (1)", roff(a))
+		}
+		fmt.Fprintf(b, ".SH SEE ALSO\n%s\n", strings.Join(also, ",\n"))
+	}
+	return b.String()
+}
+
+// runDocs implements the hidden 'kosh docs' command, which writes a complete
+// command reference generated from the same command tree that --help
+// describes
+func runDocs(c Config, args []string) error {
+	flags := flag.NewFlagSet("kosh "+docsCommand, flag.ContinueOnError)
+	format := flags.String("format", "markdown", "Output format: man or markdown")
+	out := flags.String("out", ".", "Directory to write the pages to")
+	if e := flags.Parse(args); e != nil {
+		return usageError("%s", e)
+	}
+
+	ext := map[string]string{"man": ".1", "markdown": ".md"}[*format]
+	if ext == "" {
+		return usageError("--format must be one of man, markdown: got '%s'", *format)
+	}
+
+	// options read their environment variables as they're declared, which
+	// would show up in the reference as defaults. An empty environment
+	// also keeps plugins out.
+	env := os.Environ()
+	os.Clearenv()
+	root := docTree(NewApp(c).Cmd, []string{"kosh"})
+	for _, kv := range env {
+		kv := strings.SplitN(kv, "=", 2)
+		os.Setenv(kv[0], kv[1])
+	}
+
+	if e := os.MkdirAll(*out, 0755); e != nil {
+		return e
+	}
+	now := time.Now()
+	for _, p := range root.flatten() {
+		var s string
+		switch *format {
+		case "man":
+			s = renderMan(p, c.Version, now)
+		default:
+			s = renderMarkdown(p)
+		}
+		if e := ioutil.WriteFile(filepath.Join(*out, p.FileName()+ext), []byte(s), 0644); e != nil {
+			return e
+		}
+	}
+	return nil
+}
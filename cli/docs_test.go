@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/stretchr/testify/assert"
+)
+
+func deviceApp() *cli.Cli {
+	app := cli.App("kosh", "Command line interface for Conch")
+	app.Command("device", "Perform actions against a single device", func(cmd *cli.Cmd) {
+		cmd.Spec = "DEVICE"
+		cmd.StringArg("DEVICE", "", "UUID or serial number of the device")
+		cmd.String(cli.StringOpt{
+			Name:   "f force",
+			Value:  "no",
+			Desc:   "Do it anyway",
+			EnvVar: "KOSH_FORCE FORCE",
+		})
+		cmd.Bool(cli.BoolOpt{Name: "spool", Desc: "Keep it for later"})
+		cmd.Command("get", "Get information about a single device", func(cmd *cli.Cmd) {})
+		cmd.Command("delete rm", "Delete the device", func(cmd *cli.Cmd) {
+			cmd.Bool(cli.BoolOpt{Name: "y", Desc: "Don't ask"})
+		})
+	})
+	return app
+}
+
+func TestDocTree(t *testing.T) {
+	root := docTree(deviceApp().Cmd, []string{"kosh"})
+	assert.Equal(t, "kosh COMMAND [arg...]", root.Usage)
+	assert.Len(t, root.Children, 1)
+
+	page := root.Children[0]
+	assert.Equal(t, []string{"kosh", "device"}, page.Path)
+	assert.Equal(t, "kosh device DEVICE COMMAND [arg...]", page.Usage)
+	assert.Equal(t, "Perform actions against a single device", page.Description)
+	assert.Equal(t, []docItem{{
+		Name: "DEVICE",
+		Desc: "UUID or serial number of the device",
+		Env:  []string{},
+	}}, page.Arguments)
+	assert.Equal(t, []docItem{{
+		Name:    "-f, --force",
+		Desc:    "Do it anyway",
+		Env:     []string{"KOSH_FORCE", "FORCE"},
+		Default: `"no"`,
+	}, {
+		Name: "--spool",
+		Desc: "Keep it for later",
+		Env:  []string{},
+	}}, page.Options)
+	assert.Equal(t, []docItem{
+		{Name: "get", Desc: "Get information about a single device"},
+		{Name: "delete, rm", Desc: "Delete the device"},
+	}, page.Commands)
+
+	del := page.Children[1]
+	assert.Equal(t, []string{"kosh", "device", "delete"}, del.Path)
+	assert.Equal(t, "kosh device delete [OPTIONS]", del.Usage)
+	assert.Equal(t, "-y", del.Options[0].Name)
+}
+
+func TestDocTreeOfKosh(t *testing.T) {
+	root := docTree(NewApp(Config{}).Cmd, []string{"kosh"})
+
+	names := map[string]bool{}
+	for _, p := range root.flatten() {
+		names[p.Name()] = true
+	}
+	assert.True(t, names["kosh device get"])
+	assert.True(t, names["kosh device-report diff"])
+}
+
+func TestRenderDocs(t *testing.T) {
+	page := docTree(deviceApp().Cmd, []string{"kosh"}).Children[0]
+	page.Children = []*docPage{
+		{Path: []string{"kosh", "device", "get"}},
+		{Path: []string{"kosh", "device", "delete"}},
+	}
+
+	md := renderMarkdown(page)
+	assert.Contains(t, md, "# kosh device\n")
+	assert.Contains(t, md, "* `-f, --force` - Do it anyway Environment: `KOSH_FORCE`, `FORCE`. Default: `no`.")
+	assert.Contains(t, md, "* [delete, rm](kosh_device_delete.md) - Delete the device")
+
+	man := renderMan(page, "1.0", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.True(t, strings.HasPrefix(man, `.TH KOSH_DEVICE 1 "January 2020" "kosh 1.0"`))
+	assert.Contains(t, man, ".B \\-f, \\-\\-force\n")
+	assert.Contains(t, man, ".SH ENVIRONMENT\n")
+	assert.Contains(t, man, ".BR kosh_device_get (1)")
+}
@@ -0,0 +1,426 @@
+package cli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/tables"
+)
+
+// doctor check statuses
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// clock skew thresholds. The Date header only has a resolution of a second.
+const (
+	skewWarn = 30 * time.Second
+	skewFail = 5 * time.Minute
+)
+
+// certExpiryWarn is how soon before the API's certificate expires that
+// doctor starts warning about it
+const certExpiryWarn = 14 * 24 * time.Hour
+
+// doctorSetting is a single effective configuration value and where it came
+// from
+type doctorSetting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+type doctorSettings []doctorSetting
+
+func (d doctorSettings) Len() int           { return len(d) }
+func (d doctorSettings) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d doctorSettings) Less(i, j int) bool { return false }
+
+// Headers returns the list of headers for the table view
+func (d doctorSettings) Headers() []string {
+	return []string{
+		"Setting",
+		"Value",
+		"Source",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (d doctorSettings) ForEach(do func([]string)) {
+	for _, s := range d {
+		do([]string{
+			s.Name,
+			s.Value,
+			s.Source,
+		})
+	}
+}
+
+// doctorCheck is the outcome of a single diagnostic check. Hint says how to
+// fix a check that didn't pass.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+type doctorChecks []doctorCheck
+
+func (d doctorChecks) Len() int           { return len(d) }
+func (d doctorChecks) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d doctorChecks) Less(i, j int) bool { return false }
+
+// Headers returns the list of headers for the table view
+func (d doctorChecks) Headers() []string {
+	return []string{
+		"Check",
+		"Status",
+		"Detail",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (d doctorChecks) ForEach(do func([]string)) {
+	for _, c := range d {
+		do([]string{
+			c.Name,
+			c.Status,
+			c.Detail,
+		})
+	}
+}
+
+// failed returns the number of checks that failed
+func (d doctorChecks) failed() int {
+	n := 0
+	for _, c := range d {
+		if c.Status == checkFail {
+			n++
+		}
+	}
+	return n
+}
+
+// doctorReport is everything kosh doctor found
+type doctorReport struct {
+	Settings doctorSettings `json:"settings"`
+	Checks   doctorChecks   `json:"checks"`
+}
+
+// optionSource returns where the value of a global option came from: the
+// command line, one of its environment variables or the default. names are
+// the option's names as given to mow.cli, e.g. "u url".
+func optionSource(args []string, names string, envVars ...string) string {
+	end := commandIndex(args)
+	if end < 0 {
+		end = len(args)
+	}
+	for i := 1; i < end; i++ {
+		a := args[i]
+		for _, n := range strings.Fields(names) {
+			if len(n) == 1 {
+				if !strings.HasPrefix(a, "--") && strings.HasPrefix(a, "-") && strings.Contains(a, n) {
+					return "flag -" + n
+				}
+				continue
+			}
+			if a == "--"+n || strings.HasPrefix(a, "--"+n+"=") {
+				return "flag --" + n
+			}
+		}
+	}
+	for _, v := range envVars {
+		if os.Getenv(v) != "" {
+			return "env " + v
+		}
+	}
+	return "default"
+}
+
+func (c Config) doctorSettings() doctorSettings {
+	urlSource := optionSource(c.args, "u url", "KOSH_URL", "CONCH_URL")
+	if urlSource == "default" {
+		urlSource = "from environment " + c.ConchENV
+	}
+
+	token := "(not set)"
+	if c.ConchToken != "" {
+		token = fmt.Sprintf("set (%d characters)", len(c.ConchToken))
+	}
+
+	configFile := configFilePath()
+	configSource := "default"
+	if os.Getenv("KOSH_CONFIG") != "" {
+		configSource = "env KOSH_CONFIG"
+	}
+	if _, e := os.Stat(configFile); os.IsNotExist(e) {
+		configFile += " (missing)"
+	}
+
+	return doctorSettings{
+		{"URL", c.ConchURL, urlSource},
+		{"Environment", c.ConchENV, optionSource(c.args, "e env", "KOSH_ENV", "CONCH_ENV")},
+		{"Token", token, optionSource(c.args, "t token", "KOSH_TOKEN", "CONCH_TOKEN")},
+		{"Config file", configFile, configSource},
+		{"Templates", templatesDir(), "default"},
+	}
+}
+
+// proxyEnv are the environment variables http.ProxyFromEnvironment reads
+var proxyEnv = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy"}
+
+// checkProxy reports the proxy, if any, that requests to the API go through
+func checkProxy(api *url.URL) doctorCheck {
+	check := doctorCheck{Name: "Proxy", Status: checkPass}
+
+	set := []string{}
+	for _, v := range proxyEnv {
+		if os.Getenv(v) != "" {
+			set = append(set, v)
+		}
+	}
+
+	proxy, e := http.ProxyFromEnvironment(&http.Request{URL: api})
+	switch {
+	case e != nil:
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("invalid proxy setting: %s", e)
+		check.Hint = "Fix or unset " + strings.Join(set, ", ")
+	case proxy != nil:
+		check.Detail = "via " + proxy.Redacted() + " (" + strings.Join(set, ", ") + ")"
+	case len(set) > 0:
+		check.Detail = "direct (" + strings.Join(set, ", ") + " set but not used for this host)"
+	default:
+		check.Detail = "direct"
+	}
+	return check
+}
+
+// checkClock compares the server's Date header with the local time half way
+// through the request
+func checkClock(server, local time.Time) doctorCheck {
+	check := doctorCheck{Name: "Clock skew", Status: checkPass}
+
+	skew := server.Sub(local).Round(time.Second)
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	check.Detail = fmt.Sprintf("local clock is %s behind the server", abs)
+	if skew < 0 {
+		check.Detail = fmt.Sprintf("local clock is %s ahead of the server", abs)
+	}
+
+	switch {
+	case abs >= skewFail:
+		check.Status = checkFail
+	case abs >= skewWarn:
+		check.Status = checkWarn
+	}
+	if check.Status != checkPass {
+		check.Hint = "Synchronize the local clock with NTP. Tokens and TLS certificates are checked against it"
+	}
+	return check
+}
+
+// checkTLS connects to the API host directly and verifies its certificate
+// chain against the system roots
+func checkTLS(api *url.URL, proxied bool) doctorCheck {
+	check := doctorCheck{Name: "TLS", Status: checkPass}
+	if api.Scheme != "https" {
+		check.Status = checkWarn
+		check.Detail = "not using TLS"
+		check.Hint = "Use an https:// URL; the token is sent in the clear"
+		return check
+	}
+
+	port := api.Port()
+	if port == "" {
+		port = "443"
+	}
+	conn, e := tls.DialWithDialer(
+		&net.Dialer{Timeout: 5 * time.Second},
+		"tcp",
+		net.JoinHostPort(api.Hostname(), port),
+		&tls.Config{ServerName: api.Hostname()},
+	)
+	if e != nil {
+		check.Status = checkFail
+		check.Detail = e.Error()
+
+		var unknown x509.UnknownAuthorityError
+		var hostname x509.HostnameError
+		var invalid x509.CertificateInvalidError
+		switch {
+		case errors.As(e, &unknown):
+			check.Hint = "The certificate isn't signed by a trusted CA. Install the CA or point SSL_CERT_FILE at it"
+		case errors.As(e, &hostname):
+			check.Hint = "The certificate is for a different host. Check the URL"
+		case errors.As(e, &invalid):
+			check.Hint = "The certificate is expired or not yet valid. Check the local clock"
+		case proxied:
+			check.Status = checkWarn
+			check.Hint = "The host can't be reached directly, which is expected when a proxy is required"
+		default:
+			check.Hint = "Check the URL and that the host is reachable"
+		}
+		return check
+	}
+	defer conn.Close()
+
+	chain := conn.ConnectionState().VerifiedChains[0]
+	leaf, root := chain[0], chain[len(chain)-1]
+	left := time.Until(leaf.NotAfter)
+	check.Detail = fmt.Sprintf(
+		"verified by %s, expires %s (%d days)",
+		root.Subject.CommonName,
+		leaf.NotAfter.Format("2006-01-02"),
+		int(left.Hours()/24),
+	)
+	if left < certExpiryWarn {
+		check.Status = checkWarn
+		check.Hint = "The API's certificate expires soon"
+	}
+	return check
+}
+
+// runDoctor runs every check in order. Checks that need the API are skipped
+// if it can't be reached.
+func (c Config) runDoctor() doctorReport {
+	report := doctorReport{Settings: c.doctorSettings()}
+	add := func(check doctorCheck) { report.Checks = append(report.Checks, check) }
+
+	api, e := url.Parse(c.ConchURL)
+	if e != nil || api.Host == "" {
+		add(doctorCheck{
+			Name:   "URL",
+			Status: checkFail,
+			Detail: fmt.Sprintf("'%s' is not a valid URL", c.ConchURL),
+			Hint:   "Set --url or KOSH_URL to the API's URL, e.g. " + productionURL,
+		})
+		return report
+	}
+
+	proxy := checkProxy(api)
+	add(proxy)
+	proxied := strings.HasPrefix(proxy.Detail, "via ")
+
+	client := c.ConchClient()
+
+	skip := func(name string) {
+		add(doctorCheck{Name: name, Status: checkSkip, Detail: "the API is unreachable"})
+	}
+
+	start := time.Now()
+	res, _, e := client.Raw(http.MethodGet, "ping", nil, nil)
+	end := time.Now()
+	if e == nil {
+		_, e = client.Ping()
+	}
+	if e != nil {
+		hint := fmt.Sprintf("Check that %s is the right URL and is reachable", c.ConchURL)
+		if proxied {
+			hint += ", and that the proxy is working"
+		}
+		add(doctorCheck{Name: "Ping", Status: checkFail, Detail: e.Error(), Hint: hint})
+		add(checkTLS(api, proxied))
+		for _, name := range []string{"Version", "Clock skew", "Token", "Admin"} {
+			skip(name)
+		}
+		return report
+	}
+	add(doctorCheck{Name: "Ping", Status: checkPass, Detail: "the API is reachable"})
+
+	add(checkTLS(api, proxied))
+
+	if v, e := client.Version(); e != nil {
+		add(doctorCheck{Name: "Version", Status: checkFail, Detail: e.Error()})
+	} else {
+		add(doctorCheck{Name: "Version", Status: checkPass, Detail: "API " + v.Version})
+	}
+
+	if date, e := http.ParseTime(res.Header.Get("Date")); e == nil {
+		add(checkClock(date, start.Add(end.Sub(start)/2)))
+	} else {
+		add(doctorCheck{Name: "Clock skew", Status: checkSkip, Detail: "the API sent no Date header"})
+	}
+
+	if c.ConchToken == "" {
+		add(doctorCheck{
+			Name:   "Token",
+			Status: checkFail,
+			Detail: "no token",
+			Hint:   "Set --token or KOSH_TOKEN. Create a token with 'kosh user tokens create NAME'",
+		})
+		skip("Admin")
+		return report
+	}
+
+	me, e := client.GetCurrentUser()
+	if e != nil {
+		check := doctorCheck{Name: "Token", Status: checkFail, Detail: e.Error()}
+		if exitCode(e) == ExitAuth {
+			check.Hint = "The token has expired or been revoked, or is for a different environment. Create a new one with 'kosh user tokens create NAME'"
+		}
+		add(check)
+		skip("Admin")
+		return report
+	}
+	add(doctorCheck{Name: "Token", Status: checkPass, Detail: fmt.Sprintf("valid for %s <%s>", me.Name, me.Email)})
+
+	admin := "not a system administrator"
+	if me.IsAdmin {
+		admin = "system administrator"
+	}
+	add(doctorCheck{Name: "Admin", Status: checkPass, Detail: admin})
+
+	return report
+}
+
+func doctorCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Check the configuration kosh is using and that it can reach and authenticate to the API.
+
+Prints the effective settings and where each came from, then a checklist with
+a hint for anything that didn't pass. Exits non zero if any check fails.`
+
+	cmd.Action = func() {
+		report := config.runDoctor()
+
+		if config.OutputJSON {
+			fmt.Println(renderJSON(report))
+		} else {
+			opts, e := config.TableOptions()
+			fatalIf(e)
+			opts.Columns = nil
+			opts.SortBy = ""
+
+			settings, _ := tables.RenderWith(report.Settings, opts)
+			checks, _ := tables.RenderWith(report.Checks, opts)
+			fmt.Println(settings)
+			fmt.Println(checks)
+
+			for _, c := range report.Checks {
+				if c.Hint != "" {
+					fmt.Printf("* %s: %s\n", c.Name, c.Hint)
+				}
+			}
+		}
+
+		if n := report.Checks.failed(); n > 0 {
+			fatalIf(fmt.Errorf("%d of %d checks failed", n, len(report.Checks)))
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionSource(t *testing.T) {
+	defer os.Setenv("KOSH_URL", os.Getenv("KOSH_URL"))
+	defer os.Setenv("CONCH_URL", os.Getenv("CONCH_URL"))
+	os.Unsetenv("KOSH_URL")
+	os.Setenv("CONCH_URL", "https://env.example.com")
+
+	assert.Equal(t, "flag --url", optionSource([]string{"kosh", "--url=x", "whoami"}, "u url", "KOSH_URL", "CONCH_URL"))
+	assert.Equal(t, "flag -u", optionSource([]string{"kosh", "-ju", "x", "whoami"}, "u url", "KOSH_URL", "CONCH_URL"))
+	assert.Equal(t, "env CONCH_URL", optionSource([]string{"kosh", "whoami", "--url"}, "u url", "KOSH_URL", "CONCH_URL"))
+	assert.Equal(t, "default", optionSource([]string{"kosh", "doctor"}, "e env", "KOSH_ENV_UNSET"))
+}
+
+func TestCheckClock(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, checkPass, checkClock(now.Add(2*time.Second), now).Status)
+
+	check := checkClock(now.Add(-time.Minute), now)
+	assert.Equal(t, checkWarn, check.Status)
+	assert.Equal(t, "local clock is 1m0s ahead of the server", check.Detail)
+	assert.NotEmpty(t, check.Hint)
+
+	assert.Equal(t, checkFail, checkClock(now.Add(time.Hour), now).Status)
+}
+
+func TestCheckTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	check := checkTLS(u, false)
+	assert.Equal(t, checkFail, check.Status, "the test server's certificate isn't trusted")
+	assert.Contains(t, check.Hint, "trusted CA")
+
+	u, _ = url.Parse("http://example.com")
+	assert.Equal(t, checkWarn, checkTLS(u, false).Status)
+}
+
+func TestRunDoctor(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ping", "/ping/":
+			w.Write([]byte(`{"status":"ok"}`))
+		case "/version/":
+			w.Write([]byte(`{"version":"v3.0.0"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized"}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := Config{ConchURL: ts.URL, ConchENV: "production", ConchToken: "expired"}
+	report := c.runDoctor()
+
+	status := map[string]string{}
+	for _, check := range report.Checks {
+		status[check.Name] = check.Status
+	}
+	assert.Equal(t, checkPass, status["Ping"])
+	assert.Equal(t, checkWarn, status["TLS"])
+	assert.Equal(t, checkPass, status["Version"])
+	assert.Equal(t, checkPass, status["Clock skew"])
+	assert.Equal(t, checkFail, status["Token"])
+	assert.Equal(t, checkSkip, status["Admin"])
+	assert.Equal(t, 1, report.Checks.failed())
+
+	c.ConchURL = "not a url"
+	report = c.runDoctor()
+	assert.Equal(t, 1, report.Checks.failed())
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+)
+
+// Exit codes returned by kosh, grouped by the class of failure so that shell
+// scripts can branch on the outcome
+const (
+	// ExitOK means the command completed successfully
+	ExitOK = 0
+	// ExitError is any failure that doesn't fit one of the classes below
+	ExitError = 1
+	// ExitUsage means the command line could not be parsed. mow.cli also uses
+	// this code for its own usage errors
+	ExitUsage = 2
+	// ExitAuth means no token was given, the token was rejected, or the user
+	// lacks the privileges required for the action
+	ExitAuth = 3
+	// ExitNotFound means the requested object does not exist
+	ExitNotFound = 4
+	// ExitValidation means the input was rejected, either locally or by the
+	// API
+	ExitValidation = 5
+	// ExitConflict means the change conflicts with the current state on the
+	// server
+	ExitConflict = 6
+	// ExitServer means the API server failed to handle the request
+	ExitServer = 7
+	// ExitNetwork means the API server could not be reached
+	ExitNetwork = 8
+)
+
+const exitCodesHelp = `Exit codes:
+  0  success
+  1  general error
+  2  usage error
+  3  authentication or authorization failure
+  4  not found
+  5  validation failure
+  6  conflict
+  7  server error
+  8  network error`
+
+// exitError associates an error with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string { return e.err.Error() }
+func (e exitError) Unwrap() error { return e.err }
+
+func usageError(format string, a ...interface{}) error {
+	return exitError{ExitUsage, fmt.Errorf(format, a...)}
+}
+
+func authError(format string, a ...interface{}) error {
+	return exitError{ExitAuth, fmt.Errorf(format, a...)}
+}
+
+func notFoundError(format string, a ...interface{}) error {
+	return exitError{ExitNotFound, fmt.Errorf(format, a...)}
+}
+
+func validationError(format string, a ...interface{}) error {
+	return exitError{ExitValidation, fmt.Errorf(format, a...)}
+}
+
+func conflictError(format string, a ...interface{}) error {
+	return exitError{ExitConflict, fmt.Errorf(format, a...)}
+}
+
+// exitCode maps an error onto one of the documented exit codes
+func exitCode(e error) int {
+	if e == nil {
+		return ExitOK
+	}
+
+	var exit exitError
+	if errors.As(e, &exit) {
+		return exit.code
+	}
+
+	var httpErr *conch.HTTPError
+	if errors.As(e, &httpErr) {
+		switch code := httpErr.StatusCode; {
+		case code == http.StatusUnauthorized, code == http.StatusForbidden:
+			return ExitAuth
+		case code == http.StatusNotFound:
+			return ExitNotFound
+		case code == http.StatusConflict:
+			return ExitConflict
+		case code == http.StatusBadRequest, code == http.StatusUnprocessableEntity:
+			return ExitValidation
+		case code >= 500:
+			return ExitServer
+		}
+		return ExitError
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(e, &urlErr) || errors.As(e, &netErr) {
+		return ExitNetwork
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(e, &syntaxErr) || errors.As(e, &typeErr) {
+		return ExitValidation
+	}
+
+	return ExitError
+}
+
+// fatalIf prints the error to STDERR and exits with the exit code for its
+// class. It does nothing if e is nil. Under --watch, anything but a usage
+// error instead stops this run of the command, and the watch loop shows the
+// error and keeps watching.
+func fatalIf(e error) {
+	if e != nil {
+		if config.watch != nil && exitCode(e) != ExitUsage {
+			panic(watchFailure{e})
+		}
+		fmt.Fprintln(os.Stderr, e)
+		config.writeStats(os.Stderr)
+		cli.Exit(exitCode(e))
+	}
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Error error
+		Code  int
+	}{
+		{"nil", nil, ExitOK},
+		{"plain", errors.New("oops"), ExitError},
+		{"usage", usageError("--name is required"), ExitUsage},
+		{"auth", authError("no token"), ExitAuth},
+		{"not found", notFoundError("no such rack"), ExitNotFound},
+		{"validation", validationError("bad phase"), ExitValidation},
+		{"conflict", conflictError("already exists"), ExitConflict},
+		{"wrapped", fmt.Errorf("context: %w", notFoundError("gone")), ExitNotFound},
+		{"401", &conch.HTTPError{StatusCode: 401}, ExitAuth},
+		{"403", &conch.HTTPError{StatusCode: 403}, ExitAuth},
+		{"404", &conch.HTTPError{StatusCode: 404}, ExitNotFound},
+		{"400", &conch.HTTPError{StatusCode: 400}, ExitValidation},
+		{"409", &conch.HTTPError{StatusCode: 409}, ExitConflict},
+		{"422", &conch.HTTPError{StatusCode: 422}, ExitValidation},
+		{"502", &conch.HTTPError{StatusCode: 502}, ExitServer},
+		{"418", &conch.HTTPError{StatusCode: 418}, ExitError},
+		{"network", &url.Error{Op: "Get", URL: "http://x", Err: errors.New("connection refused")}, ExitNetwork},
+		{"bad json", &json.SyntaxError{}, ExitValidation},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Code, exitCode(test.Error))
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	cli "github.com/jawher/mow.cli"
+)
+
+// pluginPrefix is the prefix of executables on PATH that kosh will dispatch
+// unknown top level commands to, in the same way as git and kubectl
+const pluginPrefix = "kosh-"
+
+// plugin is an external kosh subcommand found on the PATH
+type plugin struct {
+	Name   string
+	Path   string
+	Status string
+}
+
+type plugins []plugin
+
+func (p plugins) Len() int           { return len(p) }
+func (p plugins) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p plugins) Less(i, j int) bool { return p[i].Name < p[j].Name }
+
+// Headers returns the list of headers for the table view
+func (p plugins) Headers() []string {
+	return []string{
+		"Name",
+		"Path",
+		"Status",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (p plugins) ForEach(do func([]string)) {
+	for _, pl := range p {
+		do([]string{
+			pl.Name,
+			pl.Path,
+			pl.Status,
+		})
+	}
+}
+
+func isExecutable(fi os.FileInfo) bool {
+	return !fi.IsDir() && fi.Mode()&0111 != 0
+}
+
+// findPlugins searches each directory in the given PATH string for
+// executables named kosh-<name>. Every executable found is returned, in PATH
+// order. Those that can't be used are marked in their Status, either because
+// the name belongs to a built in command or because an earlier directory in
+// the PATH has a plugin with the same name.
+func findPlugins(path string, builtins map[string]bool) plugins {
+	found := plugins{}
+	seen := map[string]string{}
+
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		files, e := ioutil.ReadDir(dir)
+		if e != nil {
+			continue
+		}
+		for _, fi := range files {
+			if !strings.HasPrefix(fi.Name(), pluginPrefix) || !isExecutable(fi) {
+				continue
+			}
+			p := plugin{
+				Name: strings.TrimPrefix(fi.Name(), pluginPrefix),
+				Path: filepath.Join(dir, fi.Name()),
+			}
+			if p.Name == "" {
+				continue
+			}
+
+			switch {
+			case builtins[p.Name]:
+				p.Status = "shadowed by built in command"
+			case seen[p.Name] != "":
+				p.Status = "shadowed by " + seen[p.Name]
+			default:
+				p.Status = "ok"
+				seen[p.Name] = p.Path
+			}
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// pluginEnv returns the environment for a plugin process: the current
+// environment plus the resolved kosh configuration
+func (c Config) pluginEnv() []string {
+	output := "text"
+	if c.OutputJSON {
+		output = "json"
+	}
+
+	self, _ := os.Executable()
+
+	return append(
+		os.Environ(),
+		"KOSH_URL="+c.ConchURL,
+		"KOSH_TOKEN="+c.ConchToken,
+		"KOSH_ENV="+c.ConchENV,
+		"KOSH_OUTPUT="+output,
+		"KOSH_JSON_ONLY="+strconv.FormatBool(c.OutputJSON),
+		"KOSH_VERBOSE="+strconv.FormatBool(c.Logger.LevelInfo),
+		"KOSH_DEBUG="+strconv.FormatBool(c.Logger.LevelDebug),
+		"KOSH_BIN="+self,
+	)
+}
+
+// passToPlugin marks the end of kosh's own arguments if the command is a
+// plugin, so that every argument after the plugin's name, even --help, is
+// passed to the plugin rather than being interpreted by kosh
+func passToPlugin(args []string, plugins map[string]string) []string {
+	i := commandIndex(args)
+	if i < 0 || plugins[args[i]] == "" {
+		return args
+	}
+	if i+1 < len(args) && args[i+1] == "--" {
+		return args
+	}
+	passed := append([]string{}, args[:i+1]...)
+	passed = append(passed, "--")
+	return append(passed, args[i+1:]...)
+}
+
+// pluginCmd returns a command that runs the given plugin, passing every
+// remaining argument through untouched
+func pluginCmd(p plugin) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		args := cmd.StringsArg("ARGS", nil, "Arguments passed to the plugin")
+		cmd.Spec = "-- [ARGS...]"
+
+		cmd.Action = func() {
+			config.Debug("Running plugin " + p.Path)
+
+			run := exec.Command(p.Path, *args...)
+			run.Stdin = os.Stdin
+			run.Stdout = os.Stdout
+			run.Stderr = os.Stderr
+			run.Env = config.pluginEnv()
+
+			if e := run.Run(); e != nil {
+				var exit *exec.ExitError
+				if errors.As(e, &exit) {
+					cli.Exit(exit.ExitCode())
+				}
+				fatalIf(e)
+			}
+		}
+	}
+}
+
+func pluginListCmd(found plugins) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		list := func() { config.Renderer()(found, nil) }
+		cmd.Action = list
+		cmd.Command("list ls", "List the plugins found on the PATH", func(cmd *cli.Cmd) {
+			cmd.Action = list
+		})
+	}
+}
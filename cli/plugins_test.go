@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindPlugins(t *testing.T) {
+	first, e := ioutil.TempDir("", "kosh-plugins")
+	assert.Nil(t, e)
+	defer os.RemoveAll(first)
+
+	second, e := ioutil.TempDir("", "kosh-plugins")
+	assert.Nil(t, e)
+	defer os.RemoveAll(second)
+
+	write := func(dir, name string, mode os.FileMode) {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode))
+	}
+
+	write(first, "kosh-labels", 0755)
+	write(first, "kosh-notes.txt", 0644)
+	write(first, "kosh-build", 0755)
+	write(second, "kosh-labels", 0755)
+	write(second, "kosh-dhcp", 0755)
+	write(second, "other", 0755)
+
+	found := findPlugins(
+		strings.Join([]string{first, second}, string(os.PathListSeparator)),
+		map[string]bool{"build": true},
+	)
+
+	status := map[string]string{}
+	for _, p := range found {
+		status[p.Path] = p.Status
+	}
+
+	assert.Equal(t, map[string]string{
+		filepath.Join(first, "kosh-build"):   "shadowed by built in command",
+		filepath.Join(first, "kosh-labels"):  "ok",
+		filepath.Join(second, "kosh-labels"): "shadowed by " + filepath.Join(first, "kosh-labels"),
+		filepath.Join(second, "kosh-dhcp"):   "ok",
+	}, status)
+}
+
+func TestPassToPlugin(t *testing.T) {
+	plugins := map[string]string{"labels": "/bin/kosh-labels"}
+
+	assert.Equal(t,
+		[]string{"kosh", "-j", "labels", "--", "--help"},
+		passToPlugin([]string{"kosh", "-j", "labels", "--help"}, plugins),
+	)
+	assert.Equal(t,
+		[]string{"kosh", "labels", "--", "a"},
+		passToPlugin([]string{"kosh", "labels", "--", "a"}, plugins),
+	)
+	assert.Equal(t,
+		[]string{"kosh", "labels", "--"},
+		passToPlugin([]string{"kosh", "labels"}, plugins),
+	)
+	assert.Equal(t,
+		[]string{"kosh", "whoami", "--help"},
+		passToPlugin([]string{"kosh", "whoami", "--help"}, plugins),
+	)
+}
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"errors"
 	"fmt"
 
 	cli "github.com/jawher/mow.cli"
@@ -39,36 +38,36 @@ func racksCmd(cmd *cli.Cmd) {
 			// `--name ""` which will pass the cli lib's requirement
 			// check but is still crap
 			if *nameOpt == "" {
-				fatalIf(errors.New("--name is required"))
+				fatalIf(usageError("--name is required"))
 			}
 
 			if *roomAliasOpt == "" {
-				fatalIf(errors.New("--room is required"))
+				fatalIf(usageError("--room is required"))
 			} else {
 				room, e := conch.GetRoomByAlias(*roomAliasOpt)
 				fatalIf(e)
 
 				if (room == types.DatacenterRoomDetailed{}) {
-					fatalIf(errors.New("could not find room"))
+					fatalIf(notFoundError("could not find room"))
 				}
 				roomID = room.ID
 			}
 
 			if *roleNameOpt == "" {
-				fatalIf(errors.New("--role is required"))
+				fatalIf(usageError("--role is required"))
 			} else {
 				role, e := conch.GetRackRoleByName(*roleNameOpt)
 				if e != nil {
 					fatalIf(e)
 				}
 				if (role == types.RackRole{}) {
-					fatalIf(errors.New("could not find rack role"))
+					fatalIf(notFoundError("could not find rack role"))
 				}
 				roleID = role.ID
 			}
 
 			if *buildNameOpt == "" {
-				fatalIf(errors.New("--build is required"))
+				fatalIf(usageError("--build is required"))
 			} else {
 				build, e := conch.GetBuildByName(*buildNameOpt)
 				if e != nil {
@@ -76,13 +75,13 @@ func racksCmd(cmd *cli.Cmd) {
 				}
 				buildID = build.ID
 			}
-			conch.CreateRack(types.RackCreate{
+			fatalIf(conch.CreateRack(types.RackCreate{
 				Name:             types.MojoRelaxedPlaceholder(*nameOpt),
 				DatacenterRoomID: roomID,
 				RackRoleID:       roleID,
 				Phase:            types.DevicePhase(*phaseOpt),
 				BuildID:          buildID,
-			})
+			}))
 		}
 	})
 }
@@ -112,7 +111,7 @@ func rackCmd(cmd *cli.Cmd) {
 			fatalIf(e)
 		}
 		if (rack == types.Rack{}) {
-			fatalIf(errors.New("could not find the rack"))
+			fatalIf(notFoundError("could not find the rack"))
 		}
 	}
 
@@ -148,7 +147,7 @@ func rackCmd(cmd *cli.Cmd) {
 					fatalIf(e)
 				}
 				if (room == types.DatacenterRoomDetailed{}) {
-					fatalIf(errors.New("could not find room"))
+					fatalIf(notFoundError("could not find room"))
 				}
 				roomID = room.ID
 			}
@@ -158,7 +157,7 @@ func rackCmd(cmd *cli.Cmd) {
 					fatalIf(e)
 				}
 				if (role == types.RackRole{}) {
-					fatalIf(errors.New("could not find rack role"))
+					fatalIf(notFoundError("could not find rack role"))
 				}
 				roleID = role.ID
 			}
@@ -181,14 +180,14 @@ func rackCmd(cmd *cli.Cmd) {
 				assetTag = &empty
 			}
 
-			conch.UpdateRack(rack.ID, types.RackUpdate{
+			fatalIf(conch.UpdateRack(rack.ID, types.RackUpdate{
 				Name:             types.MojoRelaxedPlaceholder(*nameOpt),
 				DatacenterRoomID: roomID,
 				RackRoleID:       roleID,
 				Phase:            types.DevicePhase(*phaseOpt),
 				SerialNumber:     serial,
 				AssetTag:         assetTag,
-			})
+			}))
 		}
 	})
 
@@ -199,7 +198,7 @@ func rackCmd(cmd *cli.Cmd) {
 		}
 
 		cmd.Action = func() {
-			conch.DeleteRack(rack.ID)
+			fatalIf(conch.DeleteRack(rack.ID))
 			fmt.Println("OK")
 		}
 	})
@@ -232,7 +231,7 @@ func rackCmd(cmd *cli.Cmd) {
 				}
 				if len(layout) > 0 {
 					if !*overwriteOpt {
-						fatalIf(errors.New("rack already has a layout. use --overwrite to force"))
+						fatalIf(conflictError("rack already has a layout. use --overwrite to force"))
 					}
 				}
 
@@ -241,8 +240,10 @@ func rackCmd(cmd *cli.Cmd) {
 					fatalIf(e)
 				}
 
-				update := conch.ReadRackLayoutUpdate(input)
-				conch.UpdateRackLayout(rack.ID, update)
+				update, e := conch.ReadRackLayoutUpdate(input)
+				fatalIf(e)
+
+				fatalIf(conch.UpdateRackLayout(rack.ID, update))
 				fmt.Println("OK")
 			}
 		})
@@ -255,8 +256,10 @@ func rackCmd(cmd *cli.Cmd) {
 			if err != nil {
 				fatalIf(err)
 			}
-			update := conch.ReadRackAssignmentUpdate(input)
-			conch.UpdateRackAssignments(rack.ID, update)
+			update, err := conch.ReadRackAssignmentUpdate(input)
+			fatalIf(err)
+
+			fatalIf(conch.UpdateRackAssignments(rack.ID, update))
 		}
 	})
 
@@ -0,0 +1,342 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/joyent/kosh/spool"
+	"github.com/qri-io/jsonschema"
+)
+
+// maxReportSize is the largest device report the relay agent accepts
+const maxReportSize = 10 << 20
+
+// relayAgent accepts device reports over HTTP, marks them as coming from the
+// relay, checks them against the DeviceReport schema and forwards them to the
+// API through a spool, so that none are lost while the API is unreachable
+type relayAgent struct {
+	Serial  types.RelaySerialNumber
+	Schema  *jsonschema.Schema
+	Spool   *spool.Spool
+	Backoff spool.Backoff
+	// Send forwards a single report to the API
+	Send func([]byte) error
+	Now  func() time.Time
+
+	mu          sync.Mutex
+	started     time.Time
+	lastForward time.Time
+	lastError   string
+	received    int
+	forwarded   int
+	rejected    int
+
+	// wake asks the forwarding loop to flush the spool now
+	wake chan struct{}
+}
+
+// relayAgentStatus is served by the agent's status endpoint
+type relayAgentStatus struct {
+	Serial      types.RelaySerialNumber `json:"serial"`
+	Started     time.Time               `json:"started"`
+	QueueDepth  int                     `json:"queue_depth"`
+	Received    int                     `json:"received"`
+	Forwarded   int                     `json:"forwarded"`
+	Rejected    int                     `json:"rejected"`
+	LastForward *time.Time              `json:"last_forward"`
+	LastError   string                  `json:"last_error,omitempty"`
+}
+
+func newRelayAgent(serial types.RelaySerialNumber, schema *jsonschema.Schema, s *spool.Spool, b spool.Backoff, send func([]byte) error) *relayAgent {
+	return &relayAgent{
+		Serial:  serial,
+		Schema:  schema,
+		Spool:   s,
+		Backoff: b,
+		Send:    send,
+		Now:     time.Now,
+		started: time.Now(),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// handler serves device reports on the API's own path, so that anything that
+// can post a report to the API can post it to the relay instead, and the
+// agent's status on /status
+func (a *relayAgent) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device_report", a.acceptReport)
+	mux.HandleFunc("/device_report/", a.acceptReport)
+	mux.HandleFunc("/status", a.status)
+	return mux
+}
+
+// writeJSON sends v as the JSON body of a response
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends an error in the same shape as the API's errors
+func writeError(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	writeJSON(w, code, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// attachRelay sets the report's relay to this agent, keeping everything else
+// in the report as it was sent
+func (a *relayAgent) attachRelay(report []byte) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if e := json.Unmarshal(report, &fields); e != nil {
+		return nil, fmt.Errorf("the device report is not a JSON object: %s", e)
+	}
+	relay, e := json.Marshal(map[string]types.RelaySerialNumber{"serial": a.Serial})
+	if e != nil {
+		return nil, e
+	}
+	fields["relay"] = relay
+	return json.Marshal(fields)
+}
+
+func (a *relayAgent) acceptReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "device reports must be POSTed")
+		return
+	}
+	body, e := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxReportSize))
+	if e != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "unable to read the device report: %s", e)
+		return
+	}
+	report, e := a.attachRelay(body)
+	if e != nil {
+		writeError(w, http.StatusBadRequest, "%s", e)
+		return
+	}
+	checks, e := lintReport(a.Schema, report)
+	if e != nil {
+		writeError(w, http.StatusBadRequest, "%s", e)
+		return
+	}
+	if checks.failures() > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":   "the device report does not match the DeviceReport schema",
+			"details": checks,
+		})
+		return
+	}
+
+	entry, e := a.Spool.Add(report)
+	if e != nil {
+		writeError(w, http.StatusInternalServerError, "unable to spool the device report: %s", e)
+		return
+	}
+	a.mu.Lock()
+	a.received++
+	a.mu.Unlock()
+	config.Info("accepted the device report for", entry.Serial)
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"serial_number": entry.Serial})
+}
+
+func (a *relayAgent) status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "the status can only be read")
+		return
+	}
+	entries, e := a.Spool.Entries()
+	if e != nil {
+		writeError(w, http.StatusInternalServerError, "unable to read the spool: %s", e)
+		return
+	}
+
+	a.mu.Lock()
+	status := relayAgentStatus{
+		Serial:     a.Serial,
+		Started:    a.started,
+		QueueDepth: len(entries),
+		Received:   a.received,
+		Forwarded:  a.forwarded,
+		Rejected:   a.rejected,
+		LastError:  a.lastError,
+	}
+	if !a.lastForward.IsZero() {
+		last := a.lastForward
+		status.LastForward = &last
+	}
+	a.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// flush forwards the spooled reports and records what happened to them
+func (a *relayAgent) flush() {
+	results, e := a.Spool.Flush(a.Backoff, a.Send)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if e != nil {
+		a.lastError = e.Error()
+		config.Warn("unable to read the spool:", e)
+		return
+	}
+	for _, r := range results {
+		switch r.Status {
+		case spool.Delivered:
+			a.forwarded++
+			a.lastForward = a.Now()
+			a.lastError = ""
+			config.Info("forwarded the device report for", r.Serial)
+		case spool.Rejected:
+			a.rejected++
+			a.lastError = r.Error.Error()
+			config.Warn("the API rejected the device report for", r.Serial+":", r.Error)
+		case spool.Pending:
+			if r.Error != nil {
+				a.lastError = r.Error.Error()
+				config.Warn("unable to forward the device report for", r.Serial+":", r.Error)
+			}
+		}
+	}
+}
+
+// forward flushes the spool whenever a report arrives and every interval,
+// to retry reports that couldn't be forwarded, until stop is closed
+func (a *relayAgent) forward(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.flush()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-a.wake:
+		}
+	}
+}
+
+func relayAgentCmd(relayArg *string) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		cmd.LongDesc = `Run a relay agent that collects device reports and forwards them to the API.
+
+The agent listens on --listen for device reports POSTed to /device_report,
+the same path as the API, so existing scripts only need to be pointed at the
+relay. Each report is marked as coming from the relay, checked against the
+DeviceReport schema and kept in the --spool directory until the API accepts
+it. Reports are forwarded in the order they arrived, retrying with a growing
+delay, and again every --interval while the API is unreachable.
+
+The relay registers itself with the API when the agent starts, using RELAY as
+its serial number or, without it, the host name.
+
+GET /status returns the number of reports waiting to be forwarded and when
+one was last forwarded.`
+
+		var (
+			listenOpt   = cmd.StringOpt("listen", ":8080", "Address to listen for device reports on")
+			spoolDir    = cmd.StringOpt("spool", "", "Directory to keep reports in until they are forwarded")
+			schemaPath  = cmd.StringOpt("schema", "", "Read the DeviceReport schema from this file instead of the API")
+			intervalOpt = cmd.StringOpt("interval", "30s", "How often to retry reports that couldn't be forwarded")
+			attempts    = cmd.IntOpt("attempts", spool.DefaultBackoff.Attempts, "Number of times to try each report before waiting for the next interval")
+			backoff     = cmd.StringOpt("backoff", spool.DefaultBackoff.Initial.String(), "How long to wait after the first failed attempt")
+			versionOpt  = cmd.StringOpt("version", config.Version, "The version of the relay")
+			sshPortOpt  = cmd.IntOpt("ssh_port port", 22, "The SSH port for the relay")
+			ipAddrOpt   = cmd.StringOpt("ipaddr ip", "", "The IP address for the relay")
+			nameOpt     = cmd.StringOpt("name", "", "The name of the relay")
+		)
+		cmd.Spec = "--spool [OPTIONS]"
+
+		cmd.Action = func() {
+			interval, e := time.ParseDuration(*intervalOpt)
+			if e != nil || interval <= 0 {
+				fatalIf(usageError("--interval must be a duration like 30s or 5m: got '%s'", *intervalOpt))
+			}
+			initial, e := time.ParseDuration(*backoff)
+			if e != nil {
+				fatalIf(usageError("--backoff must be a duration like 1s or 500ms: got '%s'", *backoff))
+			}
+			if *attempts < 1 {
+				fatalIf(usageError("--attempts must be at least 1"))
+			}
+
+			serial := *relayArg
+			if serial == "" {
+				serial, e = os.Hostname()
+				fatalIf(e)
+			}
+
+			client := config.ConchClient()
+			var schemaJSON []byte
+			if *schemaPath != "" {
+				schemaJSON, e = ioutil.ReadFile(*schemaPath)
+			} else {
+				schemaJSON, e = client.GetSchemaJSON(deviceReportSchema)
+			}
+			fatalIf(e)
+			schema, e := parseSchema(schemaJSON)
+			fatalIf(e)
+
+			s, e := spool.New(*spoolDir)
+			fatalIf(e)
+
+			// the agent keeps reports until the API is back, so it starts
+			// even if the API can't be reached to register it
+			if e := client.RegisterRelay(serial, types.RegisterRelay{
+				Serial:  types.RelaySerialNumber(serial),
+				Version: *versionOpt,
+				Ipaddr:  *ipAddrOpt,
+				Name:    types.NonEmptyString(*nameOpt),
+				SSHPort: types.NonNegativeInteger(*sshPortOpt),
+			}); e != nil {
+				config.Warn("unable to register relay", serial+":", e)
+			}
+
+			agent := newRelayAgent(
+				types.RelaySerialNumber(serial),
+				schema,
+				s,
+				spool.Backoff{Attempts: *attempts, Initial: initial, Max: spool.DefaultBackoff.Max},
+				func(report []byte) error { return client.SendDeviceReport(bytes.NewReader(report)) },
+			)
+
+			// reports stay in the spool until they are delivered, so the
+			// forwarding loop can be stopped at any point
+			stop := make(chan struct{})
+			go agent.forward(interval, stop)
+
+			server := &http.Server{Addr: *listenOpt, Handler: agent.handler()}
+			go func() {
+				signals := make(chan os.Signal, 1)
+				signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+				<-signals
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				_ = server.Shutdown(ctx)
+			}()
+
+			config.Info("relay", serial, "listening on", *listenOpt)
+			if e := server.ListenAndServe(); e != http.ErrServerClosed {
+				fatalIf(e)
+			}
+			close(stop)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joyent/kosh/spool"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRelayAgent(t *testing.T, send func([]byte) error) *relayAgent {
+	schema, e := parseSchema([]byte(testReportSchema))
+	assert.Nil(t, e)
+	s, e := spool.New(t.TempDir())
+	assert.Nil(t, e)
+	s.Sleep = func(time.Duration) {}
+	return newRelayAgent("R1", schema, s, spool.Backoff{Attempts: 2}, send)
+}
+
+func relayAgentStatusOf(t *testing.T, a *relayAgent) relayAgentStatus {
+	w := httptest.NewRecorder()
+	a.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	status := relayAgentStatus{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &status))
+	return status
+}
+
+func TestRelayAgentAcceptReport(t *testing.T) {
+	a := newTestRelayAgent(t, nil)
+	report := `{"serial_number":"S1","system_uuid":"c0ffee00-0000-4000-8000-000000000001","bios_version":"1.0","relay":{"serial":"OTHER"}}`
+
+	w := httptest.NewRecorder()
+	a.handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/device_report/", strings.NewReader(report)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	entries, e := a.Spool.Entries()
+	assert.Nil(t, e)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "S1", entries[0].Serial)
+
+	spooled, e := ioutil.ReadFile(entries[0].Path)
+	assert.Nil(t, e)
+	assert.JSONEq(t, `{
+		"serial_number": "S1",
+		"system_uuid": "c0ffee00-0000-4000-8000-000000000001",
+		"bios_version": "1.0",
+		"relay": {"serial": "R1"}
+	}`, string(spooled))
+
+	status := relayAgentStatusOf(t, a)
+	assert.Equal(t, 1, status.QueueDepth)
+	assert.Equal(t, 1, status.Received)
+	assert.Nil(t, status.LastForward)
+}
+
+func TestRelayAgentAcceptSameReportTwice(t *testing.T) {
+	a := newTestRelayAgent(t, nil)
+	report := `{"serial_number":"S1","system_uuid":"c0ffee00-0000-4000-8000-000000000001","bios_version":"1.0"}`
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		a.handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/device_report", strings.NewReader(report)))
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	}
+
+	status := relayAgentStatusOf(t, a)
+	assert.Equal(t, 1, status.QueueDepth, "a report sent again is only spooled once")
+	assert.Equal(t, 2, status.Received)
+}
+
+func TestRelayAgentRejectsInvalidReports(t *testing.T) {
+	a := newTestRelayAgent(t, nil)
+	tests := []struct {
+		Method string
+		Body   string
+		Code   int
+	}{
+		{http.MethodPost, `{"serial_number":"S1"}`, http.StatusBadRequest},
+		{http.MethodPost, `[]`, http.StatusBadRequest},
+		{http.MethodPost, `{`, http.StatusBadRequest},
+		{http.MethodGet, ``, http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		a.handler().ServeHTTP(w, httptest.NewRequest(test.Method, "/device_report", strings.NewReader(test.Body)))
+		assert.Equal(t, test.Code, w.Code, test.Body)
+	}
+
+	entries, e := a.Spool.Entries()
+	assert.Nil(t, e)
+	assert.Empty(t, entries)
+}
+
+func TestRelayAgentFlush(t *testing.T) {
+	down := true
+	sent := []string{}
+	a := newTestRelayAgent(t, func(report []byte) error {
+		if down {
+			return &url.Error{Op: "Post", URL: "https://conch/device_report", Err: fmt.Errorf("connection refused")}
+		}
+		sent = append(sent, string(report))
+		return nil
+	})
+	_, e := a.Spool.Add([]byte(`{"serial_number":"S1"}`))
+	assert.Nil(t, e)
+
+	a.flush()
+	status := relayAgentStatusOf(t, a)
+	assert.Equal(t, 1, status.QueueDepth)
+	assert.Equal(t, `Post "https://conch/device_report": connection refused`, status.LastError)
+	assert.Nil(t, status.LastForward)
+
+	down = false
+	a.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	a.flush()
+	status = relayAgentStatusOf(t, a)
+	assert.Equal(t, 0, status.QueueDepth)
+	assert.Equal(t, 1, status.Forwarded)
+	assert.Equal(t, "", status.LastError)
+	assert.Equal(t, a.Now(), *status.LastForward)
+	assert.Equal(t, []string{`{"serial_number":"S1"}`}, sent)
+}
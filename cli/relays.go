@@ -1,8 +1,6 @@
 package cli
 
 import (
-	"errors"
-
 	cli "github.com/jawher/mow.cli"
 	"github.com/joyent/kosh/conch"
 	"github.com/joyent/kosh/conch/types"
@@ -28,33 +26,39 @@ func relayCmd(cmd *cli.Cmd) {
 	var conch *conch.Client
 	var display func(interface{}, error)
 
-	var relay types.Relay
 	relayArg := cmd.StringArg(
 		"RELAY",
 		"",
 		"ID of the relay",
 	)
 
-	cmd.Spec = "RELAY"
+	// RELAY is optional so that "relay agent" can default to the host name
+	cmd.Spec = "[RELAY]"
 
 	cmd.Before = func() {
 		conch = config.ConchClient()
 		display = config.Renderer()
+	}
 
-		var e error
-		relay, e = conch.GetRelayBySerial(*relayArg)
+	// getRelay fetches the relay the other commands act on
+	getRelay := func() types.Relay {
+		if *relayArg == "" {
+			fatalIf(usageError("RELAY is required"))
+		}
+		relay, e := conch.GetRelayBySerial(*relayArg)
 		if e != nil {
 			fatalIf(e)
 		}
 		if (relay == types.Relay{}) {
-			fatalIf(errors.New("relay not found"))
+			fatalIf(notFoundError("relay not found"))
 		}
+		return relay
 	}
 	// default action is to display the relay
-	cmd.Action = func() { display(relay, nil) }
+	cmd.Action = func() { display(getRelay(), nil) }
 
 	cmd.Command("get", "Get data about a single relay", func(cmd *cli.Cmd) {
-		cmd.Action = func() { display(relay, nil) }
+		cmd.Action = func() { display(getRelay(), nil) }
 	})
 
 	cmd.Command("register", "Register a relay with the API", func(cmd *cli.Cmd) {
@@ -66,19 +70,24 @@ func relayCmd(cmd *cli.Cmd) {
 		)
 
 		cmd.Action = func() {
-			conch.RegisterRelay(*relayArg, types.RegisterRelay{
+			if *relayArg == "" {
+				fatalIf(usageError("RELAY is required"))
+			}
+			fatalIf(conch.RegisterRelay(*relayArg, types.RegisterRelay{
 				Version: *versionOpt,
 				Ipaddr:  *ipAddrOpt,
 				Name:    types.NonEmptyString(*nameOpt),
 				SSHPort: types.NonNegativeInteger(*sshPortOpt),
-			})
+			}))
 		}
 	})
 
 	cmd.Command("delete rm", "Delete a relay", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
-			conch.DeleteRelay(relay.ID.String())
+			fatalIf(conch.DeleteRelay(getRelay().ID.String()))
 			display(conch.GetAllRelays())
 		}
 	})
+
+	cmd.Command("agent", "Accept device reports and forward them to the API", relayAgentCmd(relayArg))
 }
@@ -0,0 +1,479 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+)
+
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+// reportChange is a single difference between two device reports. Component
+// names the part of the device that changed, e.g. "disk SERIAL" or
+// "interface eth0".
+type reportChange struct {
+	Component string `json:"component"`
+	Change    string `json:"change"`
+	Field     string `json:"field,omitempty"`
+	Before    string `json:"before,omitempty"`
+	After     string `json:"after,omitempty"`
+}
+
+// reportChanges are kept in the order they were found: the device itself,
+// then disks, DIMMs, interfaces and temperatures
+type reportChanges []reportChange
+
+func (r reportChanges) Len() int           { return len(r) }
+func (r reportChanges) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r reportChanges) Less(i, j int) bool { return false }
+
+// Headers returns the list of headers for the table view
+func (r reportChanges) Headers() []string {
+	return []string{
+		"Component",
+		"Change",
+		"Field",
+		"Before",
+		"After",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (r reportChanges) ForEach(do func([]string)) {
+	for _, c := range r {
+		do([]string{c.Component, c.Change, c.Field, c.Before, c.After})
+	}
+}
+
+// toFields returns the JSON fields of v, decoded generically
+func toFields(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	b, e := json.Marshal(v)
+	if e != nil {
+		return fields
+	}
+	_ = json.Unmarshal(b, &fields)
+	return fields
+}
+
+// fieldString formats a decoded JSON value for display and comparison. A
+// number given as a string, as reporters often do for slots and temperatures,
+// reads the same as the number.
+func fieldString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return renderJSON(t)
+	}
+}
+
+// describe summarises the named fields of a component that was added or
+// removed, skipping any that are empty
+func describe(fields map[string]interface{}, names ...string) string {
+	bits := []string{}
+	for _, n := range names {
+		if v := fieldString(fields[n]); v != "" {
+			bits = append(bits, n+" "+v)
+		}
+	}
+	return strings.Join(bits, ", ")
+}
+
+// diffFields compares every field of two versions of a component
+func diffFields(component string, a, b interface{}) reportChanges {
+	was, now := toFields(a), toFields(b)
+	names := []string{}
+	for k := range was {
+		names = append(names, k)
+	}
+	for k := range now {
+		if _, ok := was[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	changes := reportChanges{}
+	for _, n := range names {
+		before, after := fieldString(was[n]), fieldString(now[n])
+		if before != after {
+			changes = append(changes, reportChange{
+				Component: component,
+				Change:    changeChanged,
+				Field:     n,
+				Before:    before,
+				After:     after,
+			})
+		}
+	}
+	return changes
+}
+
+// unmatched returns the sorted keys of a that aren't in b
+func unmatched(a, b map[string]map[string]interface{}) []string {
+	keys := []string{}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffComponents compares two sets of components keyed by their identity,
+// named idField, e.g. disks by serial number. Components that only appear on
+// one side are paired up if they have the same non-empty pairKey, e.g. a disk
+// swapped in the same slot, and compared as one. The rest are added or
+// removed, summarised by the summary fields.
+func diffComponents(kind, idField string, a, b map[string]map[string]interface{}, pairKey func(map[string]interface{}) string, summary ...string) reportChanges {
+	changes := reportChanges{}
+	keys := []string{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if now, ok := b[k]; ok {
+			changes = append(changes, diffFields(kind+" "+k, a[k], now)...)
+		}
+	}
+
+	removed, added := unmatched(a, b), unmatched(b, a)
+	paired := map[string]bool{}
+	for _, r := range removed {
+		key := pairKey(a[r])
+		if key == "" {
+			continue
+		}
+		for _, n := range added {
+			if paired[n] || pairKey(b[n]) != key {
+				continue
+			}
+			paired[r], paired[n] = true, true
+			changes = append(changes, reportChange{
+				Component: kind + " " + key,
+				Change:    changeChanged,
+				Field:     idField,
+				Before:    r,
+				After:     n,
+			})
+			changes = append(changes, diffFields(kind+" "+key, a[r], b[n])...)
+			break
+		}
+	}
+
+	for _, r := range removed {
+		if !paired[r] {
+			changes = append(changes, reportChange{
+				Component: kind + " " + r,
+				Change:    changeRemoved,
+				Before:    describe(a[r], summary...),
+			})
+		}
+	}
+	for _, n := range added {
+		if !paired[n] {
+			changes = append(changes, reportChange{
+				Component: kind + " " + n,
+				Change:    changeAdded,
+				After:     describe(b[n], summary...),
+			})
+		}
+	}
+	return changes
+}
+
+// diskSlot identifies where a disk is, so that a disk replaced with another
+// can be matched to the one it replaced
+func diskSlot(disk map[string]interface{}) string {
+	slot := fieldString(disk["slot"])
+	if slot == "" {
+		return ""
+	}
+	if enclosure := fieldString(disk["enclosure"]); enclosure != "" {
+		return "slot " + enclosure + ":" + slot
+	}
+	return "slot " + slot
+}
+
+// interfaceMAC identifies an interface that was renamed
+func interfaceMAC(iface map[string]interface{}) string {
+	mac := fieldString(iface["mac"])
+	if mac == "" {
+		return ""
+	}
+	return "mac " + mac
+}
+
+func noPair(map[string]interface{}) string { return "" }
+
+// diffReports compares two device reports: the BIOS version, SKU and
+// product, disks by serial number (or slot, if a disk was swapped), DIMMs by
+// locator, interfaces by name (or MAC, if an interface was renamed) and
+// temperatures.
+func diffReports(a, b types.DeviceReport) reportChanges {
+	changes := reportChanges{}
+	for _, field := range []struct{ name, before, after string }{
+		{"bios_version", a.BiosVersion, b.BiosVersion},
+		{"sku", a.Sku, b.Sku},
+		{"product_name", a.ProductName, b.ProductName},
+	} {
+		if field.before != field.after {
+			changes = append(changes, reportChange{
+				Component: "device",
+				Change:    changeChanged,
+				Field:     field.name,
+				Before:    field.before,
+				After:     field.after,
+			})
+		}
+	}
+
+	disks := func(r types.DeviceReport) map[string]map[string]interface{} {
+		m := map[string]map[string]interface{}{}
+		for serial, d := range r.Disks {
+			m[serial] = toFields(d)
+		}
+		return m
+	}
+	changes = append(changes, diffComponents("disk", "serial_number", disks(a), disks(b), diskSlot, "slot", "model", "size")...)
+
+	dimms := func(r types.DeviceReport) map[string]map[string]interface{} {
+		m := map[string]map[string]interface{}{}
+		for i, d := range r.Dimms {
+			locator := d.MemoryLocator
+			if locator == "" {
+				locator = fmt.Sprintf("#%d", i)
+			}
+			m[locator] = toFields(d)
+		}
+		return m
+	}
+	changes = append(changes, diffComponents("dimm", "memory-locator", dimms(a), dimms(b), noPair, "memory-size", "memory-serial-number")...)
+
+	interfaces := func(r types.DeviceReport) map[string]map[string]interface{} {
+		m := map[string]map[string]interface{}{}
+		for name, iface := range r.Interfaces {
+			m[name] = toFields(iface)
+		}
+		return m
+	}
+	changes = append(changes, diffComponents("interface", "name", interfaces(a), interfaces(b), interfaceMAC, "mac", "ipaddr")...)
+
+	var tempA, tempB interface{} = struct{}{}, struct{}{}
+	if a.Temp != nil {
+		tempA = a.Temp
+	}
+	if b.Temp != nil {
+		tempB = b.Temp
+	}
+	changes = append(changes, diffFields("temp", tempA, tempB)...)
+	return changes
+}
+
+// patchOp is a single JSON Patch (RFC 6902) operation
+type patchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// patchValue keeps a null value in an add or replace operation
+func patchValue(v interface{}) interface{} {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	return v
+}
+
+// pointerEscape escapes a key for use in a JSON Pointer
+func pointerEscape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// diffJSON appends the operations that turn a into b to ops. Objects are
+// compared key by key and arrays of the same length item by item; anything
+// else that differs is replaced.
+func diffJSON(path string, a, b interface{}, ops []patchOp) []patchOp {
+	switch was := a.(type) {
+	case map[string]interface{}:
+		now, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := []string{}
+		for k := range was {
+			keys = append(keys, k)
+		}
+		for k := range now {
+			if _, ok := was[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := path + "/" + pointerEscape(k)
+			before, inA := was[k]
+			after, inB := now[k]
+			switch {
+			case !inB:
+				ops = append(ops, patchOp{Op: "remove", Path: p})
+			case !inA:
+				ops = append(ops, patchOp{Op: "add", Path: p, Value: patchValue(after)})
+			default:
+				ops = diffJSON(p, before, after, ops)
+			}
+		}
+		return ops
+	case []interface{}:
+		now, ok := b.([]interface{})
+		if !ok || len(now) != len(was) {
+			break
+		}
+		for i := range was {
+			ops = diffJSON(fmt.Sprintf("%s/%d", path, i), was[i], now[i], ops)
+		}
+		return ops
+	}
+	if !reflect.DeepEqual(a, b) {
+		ops = append(ops, patchOp{Op: "replace", Path: path, Value: patchValue(b)})
+	}
+	return ops
+}
+
+// reportPatch returns the JSON Patch that turns report a into report b
+func reportPatch(a, b types.DeviceReport) []patchOp {
+	return diffJSON("", toFields(a), toFields(b), []patchOp{})
+}
+
+// readReportFile reads a device report from a JSON file, or STDIN for '-'
+func readReportFile(path string) (types.DeviceReport, error) {
+	report := types.DeviceReport{}
+	r, e := getInputReader(path)
+	if e != nil {
+		return report, e
+	}
+	if e := json.NewDecoder(r).Decode(&report); e != nil {
+		return report, validationError("unable to read the device report in %s: %s", path, e)
+	}
+	return report, nil
+}
+
+// loadReport reads a device report from the API if ref is a report ID, or
+// otherwise from the file it names
+func loadReport(c *conch.Client, ref string) (types.DeviceReport, error) {
+	report := types.DeviceReport{}
+	if _, e := uuid.FromString(ref); e != nil {
+		return readReportFile(ref)
+	}
+	row, e := c.GetDeviceReport(ref)
+	if e != nil {
+		return report, e
+	}
+	b, e := json.Marshal(row.Report)
+	if e != nil {
+		return report, e
+	}
+	return report, json.Unmarshal(b, &report)
+}
+
+// showReportDiff renders the differences between two reports, as a change
+// list or, with patch, a JSON Patch
+func showReportDiff(a, b types.DeviceReport, patch bool) {
+	if patch {
+		out, e := json.MarshalIndent(reportPatch(a, b), "", "  ")
+		fatalIf(e)
+		fmt.Println(string(formatJSON(out, config.OutputJSON)))
+		return
+	}
+
+	changes := diffReports(a, b)
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stderr, "No differences")
+	}
+	config.Renderer()(changes, nil)
+}
+
+const reportDiffDesc = `Disks are matched by serial number, or by slot when a disk was swapped for
+another; DIMMs by locator; and interfaces by name, or by MAC address when an
+interface was renamed. The BIOS version, SKU, product name and temperatures
+are compared too.
+
+--patch prints a JSON Patch (RFC 6902) that turns the first report into the
+second instead of the list of changes.`
+
+func deviceReportDiffCmd(id *string) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		cmd.LongDesc = `Show what changed between two reports for this device.
+
+Each report is a device report ID or the path to a report JSON file. The
+second report is the device's latest report unless it is given too. The API
+has no way to list a device's earlier reports, so there is no default for
+the first report: it must be given.
+
+` + reportDiffDesc
+
+		reportA := cmd.StringArg("REPORT_A", "", "ID or JSON file of the earlier report")
+		reportB := cmd.StringArg("REPORT_B", "", "ID or JSON file of the later report. Defaults to the latest report")
+		patch := cmd.BoolOpt("patch", false, "Print a JSON Patch instead of a list of changes")
+		cmd.Spec = "[OPTIONS] [REPORT_A] [REPORT_B] [OPTIONS]"
+
+		cmd.Action = func() {
+			if *reportA == "" {
+				fatalIf(usageError("REPORT_A is needed: the API only returns a device's latest report, so give the ID or JSON file of an earlier one to compare it with"))
+			}
+			client := config.ConchClient()
+			a, e := loadReport(client, *reportA)
+			fatalIf(e)
+
+			var b types.DeviceReport
+			if *reportB == "" {
+				d, e := client.GetDeviceBySerial(*id)
+				fatalIf(e)
+				b = d.LatestReport
+			} else {
+				b, e = loadReport(client, *reportB)
+				fatalIf(e)
+			}
+			showReportDiff(a, b, *patch)
+		}
+	}
+}
+
+func reportDiffCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Show what changed between two device report JSON files.
+
+` + reportDiffDesc
+
+	fileA := cmd.StringArg("FILE_A", "", "JSON file of the earlier report. '-' indicates STDIN")
+	fileB := cmd.StringArg("FILE_B", "", "JSON file of the later report")
+	patch := cmd.BoolOpt("patch", false, "Print a JSON Patch instead of a list of changes")
+	cmd.Spec = "[OPTIONS] FILE_A FILE_B [OPTIONS]"
+
+	cmd.Action = func() {
+		a, e := readReportFile(*fileA)
+		fatalIf(e)
+		b, e := readReportFile(*fileB)
+		fatalIf(e)
+		showReportDiff(a, b, *patch)
+	}
+}
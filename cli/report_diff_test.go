@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeReport(t *testing.T, s string) types.DeviceReport {
+	report := types.DeviceReport{}
+	assert.Nil(t, json.Unmarshal([]byte(s), &report))
+	return report
+}
+
+func TestDiffReports(t *testing.T) {
+	a := decodeReport(t, `{
+		"bios_version": "1.0",
+		"sku": "SKU1",
+		"disks": {
+			"D1": {"slot": 0, "model": "X", "firmware": "A"},
+			"D2": {"slot": "1", "enclosure": 2, "model": "X"},
+			"D3": {"slot": 5, "model": "X"}
+		},
+		"dimms": [
+			{"memory-locator": "A1", "memory-serial-number": "M1", "memory-size": 32},
+			{"memory-locator": "A2", "memory-serial-number": "M2", "memory-size": 32}
+		],
+		"interfaces": {
+			"eth0": {"mac": "00:00:00:00:00:01", "product": "P", "vendor": "V"},
+			"eth1": {"mac": "00:00:00:00:00:02", "product": "P", "vendor": "V"}
+		},
+		"temp": {"cpu0": "50", "cpu1": 51}
+	}`)
+	b := decodeReport(t, `{
+		"bios_version": "1.1",
+		"sku": "SKU1",
+		"disks": {
+			"D1": {"slot": 0, "model": "X", "firmware": "B"},
+			"D4": {"slot": 1, "enclosure": "2", "model": "Y"}
+		},
+		"dimms": [
+			{"memory-locator": "A1", "memory-serial-number": "M9", "memory-size": 32}
+		],
+		"interfaces": {
+			"eth0": {"mac": "00:00:00:00:00:01", "product": "P", "vendor": "V"},
+			"net1": {"mac": "00:00:00:00:00:02", "product": "P", "vendor": "V"}
+		},
+		"temp": {"cpu0": 50, "cpu1": 60}
+	}`)
+
+	assert.Equal(t, reportChanges{
+		{Component: "device", Change: "changed", Field: "bios_version", Before: "1.0", After: "1.1"},
+		{Component: "disk D1", Change: "changed", Field: "firmware", Before: "A", After: "B"},
+		{Component: "disk slot 2:1", Change: "changed", Field: "serial_number", Before: "D2", After: "D4"},
+		{Component: "disk slot 2:1", Change: "changed", Field: "model", Before: "X", After: "Y"},
+		{Component: "disk D3", Change: "removed", Before: "slot 5, model X"},
+		{Component: "dimm A1", Change: "changed", Field: "memory-serial-number", Before: "M1", After: "M9"},
+		{Component: "dimm A2", Change: "removed", Before: "memory-size 32, memory-serial-number M2"},
+		{Component: "interface mac 00:00:00:00:00:02", Change: "changed", Field: "name", Before: "eth1", After: "net1"},
+		{Component: "temp", Change: "changed", Field: "cpu1", Before: "51", After: "60"},
+	}, diffReports(a, b))
+
+	assert.Empty(t, diffReports(a, a))
+}
+
+func TestReportPatch(t *testing.T) {
+	a := decodeReport(t, `{"bios_version": "1.0", "disks": {"D1": {"slot": 0}, "a/b": {"slot": 1}}}`)
+	b := decodeReport(t, `{"bios_version": "1.1", "disks": {"D1": {"slot": 3}, "D~2": {"slot": 1}}}`)
+
+	out, e := json.Marshal(reportPatch(a, b))
+	assert.Nil(t, e)
+	assert.JSONEq(t, `[
+		{"op": "replace", "path": "/bios_version", "value": "1.1"},
+		{"op": "replace", "path": "/disks/D1/slot", "value": 3},
+		{"op": "add", "path": "/disks/D~02", "value": {"slot": 1}},
+		{"op": "remove", "path": "/disks/a~1b"}
+	]`, string(out))
+
+	out, e = json.Marshal(reportPatch(a, a))
+	assert.Nil(t, e)
+	assert.Equal(t, "[]", string(out))
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	cli "github.com/jawher/mow.cli"
+)
+
+// scrubber replaces the identifiers in device reports with pseudonyms. The
+// same identifier always gets the same pseudonym for the same key, so reports
+// scrubbed together can still be compared.
+type scrubber struct {
+	key []byte
+}
+
+// token derives the bytes a pseudonym is made from
+func (s scrubber) token(value string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(value))
+	return h.Sum(nil)
+}
+
+// name returns a pseudonym like "disk-3f9a0c21b7e4"
+func (s scrubber) name(prefix, value string) string {
+	return prefix + "-" + hex.EncodeToString(s.token(value)[:6])
+}
+
+// uuid returns a pseudonym that is a random (version 4) UUID
+func (s scrubber) uuid(value string) string {
+	b := s.token(strings.ToLower(value))[:16]
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	id, _ := uuid.FromBytes(b)
+	return id.String()
+}
+
+// mac returns a pseudonym that is a locally administered unicast MAC address
+func (s scrubber) mac(value string) string {
+	b := s.token(strings.ToLower(strings.ReplaceAll(value, "-", ":")))[:6]
+	b[0] = b[0]&0xfc | 0x02
+	return net.HardwareAddr(b).String()
+}
+
+// ip returns a pseudonym that is a private address of the same family
+func (s scrubber) ip(value string) string {
+	addr := net.ParseIP(value)
+	if addr == nil {
+		return s.name("ip", value)
+	}
+	b := s.token(addr.String())
+	if addr.To4() != nil {
+		return net.IPv4(10, b[0], b[1], b[2]).String()
+	}
+	pseudonym := make(net.IP, net.IPv6len)
+	pseudonym[0] = 0xfd
+	copy(pseudonym[1:], b)
+	return pseudonym.String()
+}
+
+// scrubField calls pseudonym on the string at key in an object, leaving it be
+// if it is missing, empty or not a string
+func scrubField(fields map[string]interface{}, key string, pseudonym func(string) string) {
+	if v, ok := fields[key].(string); ok && v != "" {
+		fields[key] = pseudonym(v)
+	}
+}
+
+// scrub replaces the serial numbers, MAC and IP addresses, UUIDs, hostname,
+// links and relay identity in a decoded device report. Everything else,
+// including the vendor and model of each component, is left as it was.
+func (s scrubber) scrub(report map[string]interface{}) {
+	device := func(v string) string { return s.name("device", v) }
+	scrubField(report, "serial_number", device)
+	scrubField(report, "system_uuid", s.uuid)
+
+	if host, ok := report["os"].(map[string]interface{}); ok {
+		scrubField(host, "hostname", func(v string) string { return s.name("host", v) })
+	}
+
+	if relay, ok := report["relay"].(map[string]interface{}); ok {
+		serial := func(v string) string { return s.name("relay", v) }
+		scrubField(relay, "serial", serial)
+		scrubField(relay, "serial_number", serial)
+		scrubField(relay, "name", serial)
+		scrubField(relay, "id", s.uuid)
+		scrubField(relay, "user_id", s.uuid)
+		scrubField(relay, "ipaddr", s.ip)
+	}
+
+	if links, ok := report["links"].([]interface{}); ok {
+		for i, link := range links {
+			if v, ok := link.(string); ok {
+				links[i] = "https://example.com/" + s.name("link", v)
+			}
+		}
+	}
+
+	if dimms, ok := report["dimms"].([]interface{}); ok {
+		for _, dimm := range dimms {
+			if fields, ok := dimm.(map[string]interface{}); ok {
+				scrubField(fields, "memory-serial-number", func(v string) string { return s.name("dimm", v) })
+			}
+		}
+	}
+
+	if disks, ok := report["disks"].(map[string]interface{}); ok {
+		scrubbed := map[string]interface{}{}
+		for serial, disk := range disks {
+			scrubbed[s.name("disk", serial)] = disk
+		}
+		report["disks"] = scrubbed
+	}
+
+	if interfaces, ok := report["interfaces"].(map[string]interface{}); ok {
+		for _, iface := range interfaces {
+			if fields, ok := iface.(map[string]interface{}); ok {
+				scrubField(fields, "mac", s.mac)
+				scrubField(fields, "peer_mac", s.mac)
+				scrubField(fields, "ipaddr", s.ip)
+			}
+		}
+	}
+}
+
+// scrubReport scrubs a device report, returning it as indented JSON
+func (s scrubber) scrubReport(b []byte) ([]byte, error) {
+	report := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	// keep numbers exactly as they were written
+	dec.UseNumber()
+	if e := dec.Decode(&report); e != nil {
+		return nil, e
+	}
+	s.scrub(report)
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// fileName names a scrubbed report after the pseudonym of its serial number,
+// e.g. device-3f9a0c21b7e4.json, so that the name doesn't give the device
+// away as the original file name often would
+func (s scrubber) fileName(scrubbed []byte) string {
+	report := struct {
+		SerialNumber string `json:"serial_number"`
+	}{}
+	if json.Unmarshal(scrubbed, &report) == nil && report.SerialNumber != "" {
+		return report.SerialNumber + ".json"
+	}
+	return s.name("report", string(scrubbed)) + ".json"
+}
+
+func reportScrubCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Replace the identifiers in device reports so that they can be shared.
+
+Serial numbers of the device, disks and DIMMs, MAC and IP addresses, the
+system UUID, hostname, links and the relay's identity are replaced with
+pseudonyms of the same shape, so the scrubbed report still matches the
+DeviceReport schema. Everything else, including the number of each component
+and their vendors and models, is kept.
+
+The same identifier always gets the same pseudonym, so reports scrubbed
+together, or separately with the same --key, can still be compared. Without
+a key, anyone with a list of serial numbers can work out which pseudonym is
+which; with a secret key they can't.
+
+A single report is printed. Several are written to --out, each named after
+the pseudonym of its serial number, e.g. device-3f9a0c21b7e4.json. Two
+reports for the same device can't be written to the same directory.`
+
+	files := cmd.StringsArg("FILE", []string{"-"}, "Paths to JSON files that define device reports. '-' indicates STDIN")
+	key := cmd.String(cli.StringOpt{
+		Name:      "key",
+		Value:     "",
+		Desc:      "Secret used to derive the pseudonyms",
+		EnvVar:    "KOSH_SCRUB_KEY",
+		HideValue: true,
+	})
+	outDir := cmd.StringOpt("out", "", "Directory to write the scrubbed reports to")
+	cmd.Spec = "[OPTIONS] [FILE...] [OPTIONS]"
+
+	cmd.Action = func() {
+		if len(*files) > 1 && *outDir == "" {
+			fatalIf(usageError("--out is needed to scrub more than one report"))
+		}
+		if *outDir != "" {
+			fatalIf(os.MkdirAll(*outDir, 0755))
+		}
+
+		s := scrubber{key: []byte(*key)}
+		// written holds the input each output file was written from
+		written := map[string]string{}
+		for _, path := range *files {
+			r, e := getInputReader(path)
+			fatalIf(e)
+			b, e := ioutil.ReadAll(r)
+			fatalIf(e)
+			scrubbed, e := s.scrubReport(b)
+			if e != nil {
+				fatalIf(validationError("unable to read the device report in %s: %s", path, e))
+			}
+
+			if *outDir == "" {
+				fmt.Println(string(formatJSON(scrubbed, config.OutputJSON)))
+				continue
+			}
+			out := filepath.Join(*outDir, s.fileName(scrubbed))
+			if first, ok := written[out]; ok {
+				fatalIf(validationError("%s and %s are reports for the same device and would both be written to %s; scrub them to different --out directories", first, path, out))
+			}
+			written[out] = path
+			fatalIf(ioutil.WriteFile(out, append(scrubbed, '\n'), 0644))
+			config.Info("scrubbed", path, "to", out)
+		}
+	}
+}
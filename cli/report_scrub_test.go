@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const testScrubReport = `{
+	"serial_number": "S1234567",
+	"system_uuid": "4c4c4544-0042-3510-8051-b3c04f4e4d32",
+	"bios_version": "2.8.2",
+	"product_name": "Joyent-Compute-Platform-3301",
+	"os": {"hostname": "host1.example.com"},
+	"relay": {"serial": "R1"},
+	"links": ["https://jira.example.com/browse/DC-1234"],
+	"dimms": [{"memory-locator": "P1-DIMMA1", "memory-serial-number": "15C7A1B2", "memory-size": 32}],
+	"disks": {
+		"S45PNA0M123456": {"model": "SAMSUNG MZ7LH480", "vendor": "ATA", "slot": "0", "size": 480},
+		"PHLJ912345672P0BGN": {"model": "INTEL SSDPE2KX020T8", "slot": 1, "temp": 31.0}
+	},
+	"interfaces": {
+		"eth0": {"mac": "00:1B:21:AA:BB:CC", "peer_mac": "a8:2b:b5:00:00:01", "ipaddr": "192.168.4.10", "vendor": "Intel", "product": "X710"},
+		"eth1": {"mac": "00:1b:21:aa:bb:cc", "ipaddr": "2001:db8::10", "vendor": "Intel", "product": "X710"}
+	}
+}`
+
+func scrubTestReport(t *testing.T, key string) map[string]interface{} {
+	b, e := scrubber{key: []byte(key)}.scrubReport([]byte(testScrubReport))
+	assert.Nil(t, e)
+	report := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(b, &report))
+	return report
+}
+
+func TestScrubReport(t *testing.T) {
+	b, e := scrubber{}.scrubReport([]byte(testScrubReport))
+	assert.Nil(t, e)
+	for _, secret := range []string{"S1234567", "4c4c4544", "host1", "R1\"", "DC-1234", "15C7A1B2", "S45PNA0M123456", "aa:bb:cc", "AA:BB:CC", "192.168.4.10", "2001:db8"} {
+		assert.NotContains(t, string(b), secret)
+	}
+	for _, kept := range []string{"Joyent-Compute-Platform-3301", "SAMSUNG MZ7LH480", "INTEL SSDPE2KX020T8", "P1-DIMMA1", "X710", `"slot": "0"`, `"temp": 31.0`} {
+		assert.Contains(t, string(b), kept)
+	}
+
+	report := scrubTestReport(t, "")
+	assert.True(t, strings.HasPrefix(report["serial_number"].(string), "device-"))
+	_, e = uuid.FromString(report["system_uuid"].(string))
+	assert.Nil(t, e)
+	assert.Len(t, report["disks"], 2)
+
+	interfaces := report["interfaces"].(map[string]interface{})
+	eth0 := interfaces["eth0"].(map[string]interface{})
+	eth1 := interfaces["eth1"].(map[string]interface{})
+	// the same MAC, however it is written, gets the same pseudonym
+	assert.Equal(t, eth0["mac"], eth1["mac"])
+	_, e = net.ParseMAC(eth0["mac"].(string))
+	assert.Nil(t, e)
+	assert.NotNil(t, net.ParseIP(eth0["ipaddr"].(string)).To4())
+	assert.Nil(t, net.ParseIP(eth1["ipaddr"].(string)).To4())
+
+	schema, e := parseSchema([]byte(testReportSchema))
+	assert.Nil(t, e)
+	checks, e := lintReport(schema, b)
+	assert.Nil(t, e)
+	assert.Equal(t, 0, checks.failures())
+}
+
+func TestScrubReportKey(t *testing.T) {
+	assert.Equal(t, scrubTestReport(t, ""), scrubTestReport(t, ""))
+	assert.Equal(t, scrubTestReport(t, "secret"), scrubTestReport(t, "secret"))
+	assert.NotEqual(t,
+		scrubTestReport(t, "")["serial_number"],
+		scrubTestReport(t, "secret")["serial_number"],
+	)
+}
+
+func TestScrubFileName(t *testing.T) {
+	s := scrubber{}
+	b, e := s.scrubReport([]byte(testScrubReport))
+	assert.Nil(t, e)
+	name := s.fileName(b)
+	assert.Equal(t, scrubTestReport(t, "")["serial_number"].(string)+".json", name)
+	assert.NotContains(t, name, "S1234567")
+
+	b, e = s.scrubReport([]byte(`{"bios_version": "2.8.2"}`))
+	assert.Nil(t, e)
+	assert.True(t, strings.HasPrefix(s.fileName(b), "report-"))
+}
@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/qri-io/jsonschema"
+)
+
+// deviceReportSchema is the name of the request schema device reports are
+// checked against
+const deviceReportSchema = "request/DeviceReport"
+
+// reportCheck is a single check of a device report, either against the
+// schema or by one of the API's validations
+type reportCheck struct {
+	Check     string `json:"check"`
+	Category  string `json:"category,omitempty"`
+	Component string `json:"component,omitempty"`
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+}
+
+// reportChecks are kept in the order they were made
+type reportChecks []reportCheck
+
+func (r reportChecks) Len() int           { return len(r) }
+func (r reportChecks) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r reportChecks) Less(i, j int) bool { return false }
+
+// Headers returns the list of headers for the table view
+func (r reportChecks) Headers() []string {
+	return []string{
+		"Check",
+		"Category",
+		"Component",
+		"Status",
+		"Message",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (r reportChecks) ForEach(do func([]string)) {
+	for _, c := range r {
+		do([]string{c.Check, c.Category, c.Component, c.Status, c.Message})
+	}
+}
+
+// failures counts the checks that didn't pass
+func (r reportChecks) failures() int {
+	n := 0
+	for _, c := range r {
+		if c.Status != "pass" {
+			n++
+		}
+	}
+	return n
+}
+
+// rewriteRefs points every "/definitions/..." reference in a schema at
+// "#/$defs/...", where the jsonschema package looks for them
+func rewriteRefs(v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if ref, ok := child.(string); ok && k == "$ref" {
+				ref = strings.TrimPrefix(ref, "#")
+				if strings.HasPrefix(ref, "/definitions/") {
+					t[k] = "#/$defs/" + strings.TrimPrefix(ref, "/definitions/")
+				}
+				continue
+			}
+			rewriteRefs(child)
+		}
+	case []interface{}:
+		for _, child := range t {
+			rewriteRefs(child)
+		}
+	}
+}
+
+// parseSchema parses a JSON Schema as served by the API. Its definitions are
+// moved to $defs so that the references to them resolve.
+func parseSchema(b []byte) (*jsonschema.Schema, error) {
+	doc := map[string]interface{}{}
+	if e := json.Unmarshal(b, &doc); e != nil {
+		return nil, validationError("unable to read the schema: %s", e)
+	}
+	if defs, ok := doc["definitions"]; ok {
+		doc["$defs"] = defs
+		delete(doc, "definitions")
+	}
+	rewriteRefs(doc)
+
+	b, e := json.Marshal(doc)
+	if e != nil {
+		return nil, e
+	}
+	schema := &jsonschema.Schema{}
+	if e := json.Unmarshal(b, schema); e != nil {
+		return nil, validationError("unable to read the schema: %s", e)
+	}
+	return schema, nil
+}
+
+// lintReport checks a device report against the schema, returning a failed
+// check for each problem or a single passing check
+func lintReport(schema *jsonschema.Schema, report []byte) (reportChecks, error) {
+	problems, e := schema.ValidateBytes(context.Background(), report)
+	if e != nil {
+		return nil, validationError("the device report is not valid JSON: %s", e)
+	}
+	if len(problems) == 0 {
+		return reportChecks{{
+			Check:   "schema",
+			Status:  "pass",
+			Message: "matches the DeviceReport schema",
+		}}, nil
+	}
+
+	checks := reportChecks{}
+	for _, p := range problems {
+		path := p.PropertyPath
+		if path == "" {
+			path = "/"
+		}
+		checks = append(checks, reportCheck{
+			Check:     "schema",
+			Component: path,
+			Status:    "fail",
+			Message:   p.Message,
+		})
+	}
+	sort.SliceStable(checks, func(i, j int) bool { return checks[i].Component < checks[j].Component })
+	return checks, nil
+}
+
+// validationChecks turns the API's validation results into checks
+func validationChecks(results types.ReportValidationResults) reportChecks {
+	checks := reportChecks{}
+	for _, r := range results.Results {
+		checks = append(checks, reportCheck{
+			Check:     "validation",
+			Category:  r.Category,
+			Component: r.Component,
+			Status:    string(r.Status),
+			Message:   r.Message,
+		})
+	}
+	if len(checks) == 0 && results.Status != "" {
+		checks = append(checks, reportCheck{
+			Check:   "validation",
+			Status:  string(results.Status),
+			Message: fmt.Sprintf("no results for %s", results.Sku),
+		})
+	}
+	return checks
+}
+
+func reportValidateCmd(cmd *cli.Cmd) {
+	cmd.LongDesc = `Check a device report before posting it.
+
+The report is first checked against the API's DeviceReport request schema,
+fetched from the API or read from --schema. If it matches, it is sent to the
+API to run the validations for its hardware product without recording
+anything. Every check is shown in a table and the exit status is non zero if
+any didn't pass.
+
+--lint-only skips sending the report. With --schema too, nothing is fetched
+from the API at all.`
+
+	filePathArg := cmd.StringArg("FILE", "-", "Path to a JSON file that defines the device report. '-' indicates STDIN")
+	schemaPath := cmd.StringOpt("schema", "", "Read the DeviceReport schema from this file instead of the API")
+	lintOnly := cmd.BoolOpt("lint-only", false, "Only check the report against the schema")
+	cmd.Spec = "[OPTIONS] [FILE] [OPTIONS]"
+
+	cmd.Action = func() {
+		input, e := getInputReader(*filePathArg)
+		fatalIf(e)
+		report, e := ioutil.ReadAll(input)
+		fatalIf(e)
+
+		var schemaJSON []byte
+		if *schemaPath != "" {
+			r, e := getInputReader(*schemaPath)
+			fatalIf(e)
+			schemaJSON, e = ioutil.ReadAll(r)
+			fatalIf(e)
+		} else {
+			schemaJSON, e = config.ConchClient().GetSchemaJSON(deviceReportSchema)
+			fatalIf(e)
+		}
+		schema, e := parseSchema(schemaJSON)
+		fatalIf(e)
+
+		checks, e := lintReport(schema, report)
+		fatalIf(e)
+
+		if checks.failures() == 0 && !*lintOnly {
+			results, e := config.ConchClient().ValidateDeviceReport(bytes.NewReader(report))
+			fatalIf(e)
+			checks = append(checks, validationChecks(results)...)
+		}
+
+		config.Renderer()(checks, nil)
+		if n := checks.failures(); n > 0 {
+			fatalIf(validationError("the device report failed %d of %d checks", n, len(checks)))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// testReportSchema is a cut down DeviceReport schema, written the way the API
+// serves them
+const testReportSchema = `{
+	"$id": "urn:request.DeviceReport.schema.json",
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"definitions": {
+		"device_serial_number": {"pattern": "^\\S+$", "type": "string"},
+		"uuid": {"pattern": "^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", "type": "string"}
+	},
+	"properties": {
+		"serial_number": {"$ref": "/definitions/device_serial_number"},
+		"system_uuid": {"$ref": "/definitions/uuid"},
+		"bios_version": {"type": "string"}
+	},
+	"required": ["serial_number", "system_uuid", "bios_version"],
+	"type": "object"
+}`
+
+func TestLintReport(t *testing.T) {
+	schema, e := parseSchema([]byte(testReportSchema))
+	assert.Nil(t, e)
+
+	checks, e := lintReport(schema, []byte(`{"serial_number": "S1", "system_uuid": "5a3c4d1e-0000-4000-8000-000000000001", "bios_version": "1"}`))
+	assert.Nil(t, e)
+	assert.Equal(t, 0, checks.failures())
+	assert.Len(t, checks, 1)
+
+	checks, e = lintReport(schema, []byte(`{"serial_number": "S 1", "system_uuid": "nope"}`))
+	assert.Nil(t, e)
+	assert.Equal(t, 3, checks.failures())
+	components := []string{}
+	for _, c := range checks {
+		components = append(components, c.Component)
+	}
+	assert.Equal(t, []string{"/", "/serial_number", "/system_uuid"}, components)
+
+	_, e = lintReport(schema, []byte(`{"serial_number":`))
+	assert.Equal(t, ExitValidation, exitCode(e))
+
+	_, e = parseSchema([]byte(`not json`))
+	assert.Equal(t, ExitValidation, exitCode(e))
+}
+
+func TestValidationChecks(t *testing.T) {
+	checks := validationChecks(types.ReportValidationResults{
+		Status: "fail",
+		Results: types.ValidationResults{
+			{Category: "BIOS", Component: "bios", Status: "pass", Message: "ok"},
+			{Category: "DISK", Component: "D1", Status: "fail", Message: "missing"},
+		},
+	})
+	assert.Equal(t, reportChecks{
+		{Check: "validation", Category: "BIOS", Component: "bios", Status: "pass", Message: "ok"},
+		{Check: "validation", Category: "DISK", Component: "D1", Status: "fail", Message: "missing"},
+	}, checks)
+	assert.Equal(t, 1, checks.failures())
+}
@@ -1,8 +1,6 @@
 package cli
 
 import (
-	"errors"
-
 	cli "github.com/jawher/mow.cli"
 	"github.com/joyent/kosh/conch"
 	"github.com/joyent/kosh/conch/types"
@@ -31,16 +29,16 @@ func rolesCmd(cmd *cli.Cmd) {
 		cmd.Spec = "--name --rack-size"
 		cmd.Action = func() {
 			if *nameOpt == "" {
-				fatalIf(errors.New("--name is required"))
+				fatalIf(usageError("--name is required"))
 			}
 
 			if *rackSizeOpt == 0 {
-				fatalIf(errors.New("--rack-size is required and cannot be 0"))
+				fatalIf(usageError("--rack-size is required and cannot be 0"))
 			}
-			conch.CreateRackRole(types.RackRoleCreate{
+			fatalIf(conch.CreateRackRole(types.RackRoleCreate{
 				Name:     types.MojoStandardPlaceholder(*nameOpt),
 				RackSize: types.PositiveInteger(*rackSizeOpt),
-			})
+			}))
 		}
 	})
 }
@@ -71,7 +69,7 @@ func roleCmd(cmd *cli.Cmd) {
 			fatalIf(e)
 		}
 		if (role == types.RackRole{}) {
-			fatalIf(errors.New("couldn't find the role"))
+			fatalIf(notFoundError("couldn't find the role"))
 		}
 	}
 
@@ -86,17 +84,17 @@ func roleCmd(cmd *cli.Cmd) {
 		)
 
 		cmd.Action = func() {
-			conch.UpdateRackRole(role.ID, types.RackRoleUpdate{
+			fatalIf(conch.UpdateRackRole(role.ID, types.RackRoleUpdate{
 				Name:     types.MojoStandardPlaceholder(*nameOpt),
 				RackSize: types.PositiveInteger(*rackSizeOpt),
-			})
+			}))
 			display(conch.GetAllRackRoles())
 		}
 	})
 
 	cmd.Command("delete", "Delete a single rack role", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
-			conch.DeleteRackRole(role.ID)
+			fatalIf(conch.DeleteRackRole(role.ID))
 			display(conch.GetAllRackRoles())
 		}
 	})
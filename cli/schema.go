@@ -16,7 +16,7 @@ func schemaCmd(cmd *cli.Cmd) {
 		display = config.Renderer()
 	}
 
-	cmd.Command("request", "View your Conch profile", func(cmd *cli.Cmd) {
+	cmd.Command("request", "Get the JSON Schema for a request body", func(cmd *cli.Cmd) {
 		name := cmd.StringArg("NAME", "", "The string name of a request schema")
 		cmd.Spec = "NAME"
 
@@ -25,7 +25,7 @@ func schemaCmd(cmd *cli.Cmd) {
 		}
 	})
 
-	cmd.Command("response", "Get the settings for the current user", func(cmd *cli.Cmd) {
+	cmd.Command("response", "Get the JSON Schema for a response body", func(cmd *cli.Cmd) {
 		name := cmd.StringArg("NAME", "", "The string name of a response schema")
 		cmd.Spec = "NAME"
 
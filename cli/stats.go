@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/tables"
+)
+
+// --stats formats
+const (
+	statsTable = "table"
+	statsJSON  = "json"
+)
+
+// slowestRequests is how many of the slowest requests the summary lists
+const slowestRequests = 5
+
+// normalizeStatsArgs rewrites a bare --stats into --stats=table, for the same
+// reason as normalizeWatchArgs
+func normalizeStatsArgs(args []string) []string {
+	normalized := make([]string, len(args))
+	copy(normalized, args)
+	for i, a := range normalized {
+		if a == "--" {
+			break
+		}
+		if a == "--stats" {
+			normalized[i] = "--stats=" + statsTable
+		}
+	}
+	return normalized
+}
+
+func milliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', 1, 64)
+}
+
+// writeStats writes the summary of every request made so far, if --stats was
+// given, and stops recording so that it's only written once even if the
+// command fails after it's been written
+func (c *Config) writeStats(w io.Writer) {
+	if c.stats == nil {
+		return
+	}
+	summary := c.stats.Summary(slowestRequests)
+	c.stats = nil
+
+	if c.Stats == statsJSON {
+		b, e := json.MarshalIndent(summary, "", "  ")
+		if e != nil {
+			fmt.Fprintln(w, e)
+			return
+		}
+		fmt.Fprintln(w, string(b))
+		return
+	}
+
+	fmt.Fprintf(
+		w,
+		"\n%d requests (%d cached, %d failed) in %sms, %d bytes\n\n",
+		summary.Count,
+		summary.Cached,
+		summary.Errors,
+		milliseconds(summary.Total),
+		summary.Bytes,
+	)
+	if summary.Count == 0 {
+		return
+	}
+
+	endpoints := [][]string{}
+	for _, e := range summary.Endpoints {
+		endpoints = append(endpoints, []string{
+			e.Method,
+			e.Endpoint,
+			strconv.Itoa(e.Count),
+			strconv.Itoa(e.Cached),
+			milliseconds(e.Total),
+			milliseconds(e.Slowest),
+			strconv.FormatInt(e.Bytes, 10),
+		})
+	}
+	fmt.Fprintln(w, tables.RenderRows(
+		[]string{"Method", "Endpoint", "Count", "Cached", "Total ms", "Slowest ms", "Bytes"},
+		endpoints,
+		tables.Options{},
+	))
+
+	slowest := [][]string{}
+	for _, r := range summary.Slowest {
+		status := strconv.Itoa(r.Status)
+		if r.Error != "" && r.Status == 0 {
+			status = r.Error
+		}
+		cached := ""
+		if r.Cached {
+			cached = "yes"
+		}
+		slowest = append(slowest, []string{
+			r.Method,
+			r.URL,
+			status,
+			milliseconds(r.Duration),
+			strconv.FormatInt(r.Bytes, 10),
+			cached,
+		})
+	}
+	fmt.Fprintln(w, "Slowest requests")
+	fmt.Fprintln(w, tables.RenderRows(
+		[]string{"Method", "URL", "Status", "ms", "Bytes", "Cached"},
+		slowest,
+		tables.Options{},
+	))
+}
+
+// startStats begins recording requests if --stats was given
+func (c *Config) startStats() error {
+	switch c.Stats {
+	case "":
+		return nil
+	case statsTable, statsJSON:
+		c.stats = conch.NewStats()
+		return nil
+	default:
+		return usageError("--stats must be one of table, json: got '%s'", c.Stats)
+	}
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeStatsArgs(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"kosh", "--stats=table", "relays"},
+		normalizeStatsArgs([]string{"kosh", "--stats", "relays"}),
+	)
+	assert.Equal(
+		t,
+		[]string{"kosh", "--stats=json", "relays"},
+		normalizeStatsArgs([]string{"kosh", "--stats=json", "relays"}),
+	)
+	assert.Equal(
+		t,
+		[]string{"kosh", "api", "--", "--stats"},
+		normalizeStatsArgs([]string{"kosh", "api", "--", "--stats"}),
+	)
+}
+
+func TestWriteStats(t *testing.T) {
+	c := Config{Stats: statsTable}
+	assert.Nil(t, c.startStats())
+	c.stats.Record(conch.RequestStat{
+		Method:   "GET",
+		URL:      "https://conch/user/me",
+		Endpoint: "/user/me",
+		Status:   200,
+		Duration: 1500 * time.Microsecond,
+		Bytes:    13,
+		Cached:   true,
+	})
+	c.stats.Record(conch.RequestStat{
+		Method:   "GET",
+		URL:      "https://conch/user/me",
+		Endpoint: "/user/me",
+		Status:   200,
+		Bytes:    13,
+	})
+
+	buf := &bytes.Buffer{}
+	c.writeStats(buf)
+	assert.Contains(t, buf.String(), "2 requests (1 cached, 0 failed) in 1.5ms, 26 bytes")
+	assert.Contains(t, buf.String(), "/user/me")
+	assert.Contains(t, buf.String(), "Slowest requests")
+
+	buf.Reset()
+	c.writeStats(buf)
+	assert.Empty(t, buf.String(), "the summary is only written once")
+
+	c.Stats = statsJSON
+	assert.Nil(t, c.startStats())
+	c.stats.Record(conch.RequestStat{Method: "GET", Endpoint: "/user/me", Status: 200})
+	c.stats.Record(conch.RequestStat{Method: "GET", Endpoint: "/user/me", Status: 404})
+	c.stats.Record(conch.RequestStat{Method: "GET", Endpoint: "/user/me", Status: 200, Cached: true})
+	c.writeStats(buf)
+	summary := conch.StatsSummary{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &summary))
+	assert.Equal(t, 3, summary.Count)
+	assert.Equal(t, 1, summary.Errors)
+	assert.Equal(t, 1, summary.Cached)
+
+	assert.NotNil(t, (&Config{Stats: "yaml"}).startStats())
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/joyent/kosh/template"
+)
+
+// templatesDir is where user templates overriding the built in ones live
+func templatesDir() string {
+	return filepath.Join(configDir(), "templates")
+}
+
+// findTemplated returns the type with a built in template with the given
+// name, ignoring case
+func findTemplated(name string) (template.Templated, error) {
+	name = strings.TrimSuffix(name, ".tmpl")
+	for _, t := range types.Templates() {
+		if strings.EqualFold(template.Name(t), name) {
+			return t, nil
+		}
+	}
+	return nil, notFoundError("no template named '%s', see 'kosh templates list'", name)
+}
+
+// editor returns the user's preferred editor, in the same order of
+// preference as git
+func editor() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if e := os.Getenv(env); e != "" {
+			return e
+		}
+	}
+	return "vi"
+}
+
+type templateList []template.Templated
+
+func (tl templateList) Len() int           { return len(tl) }
+func (tl templateList) Swap(i, j int)      { tl[i], tl[j] = tl[j], tl[i] }
+func (tl templateList) Less(i, j int) bool { return template.Name(tl[i]) < template.Name(tl[j]) }
+
+// Headers returns the list of headers for the table view
+func (tl templateList) Headers() []string {
+	return []string{
+		"Name",
+		"Source",
+	}
+}
+
+// ForEach iterates over each item in the list and applies a function to it
+func (tl templateList) ForEach(do func([]string)) {
+	for _, t := range tl {
+		source := "built in"
+		if _, e := os.Stat(template.Path(t)); e == nil {
+			source = template.Path(t)
+		}
+		do([]string{template.Name(t), source})
+	}
+}
+
+func templatesCmd(cmd *cli.Cmd) {
+	list := func() { config.Renderer()(templateList(types.Templates()), nil) }
+
+	cmd.Action = list
+
+	cmd.Command("list ls", "List the output templates and whether they are overridden", func(cmd *cli.Cmd) {
+		cmd.Action = list
+	})
+
+	cmd.Command("show", "Print a template, as a starting point for your own", func(cmd *cli.Cmd) {
+		nameArg := cmd.StringArg("NAME", "", "Name of the template, as shown by 'templates list'")
+		builtIn := cmd.BoolOpt("default d", false, "Show the built in template even if it is overridden")
+		cmd.Spec = "[--default] NAME"
+
+		cmd.Action = func() {
+			t, e := findTemplated(*nameArg)
+			fatalIf(e)
+
+			text := t.Template()
+			if !*builtIn {
+				text, _, e = template.Lookup(t)
+				fatalIf(e)
+			}
+			fmt.Print(text)
+		}
+	})
+
+	cmd.Command("edit", "Edit the user template for a type, starting from the built in one", func(cmd *cli.Cmd) {
+		nameArg := cmd.StringArg("NAME", "", "Name of the template, as shown by 'templates list'")
+		cmd.Spec = "NAME"
+
+		cmd.Action = func() {
+			t, e := findTemplated(*nameArg)
+			fatalIf(e)
+
+			path := template.Path(t)
+			if _, e := os.Stat(path); os.IsNotExist(e) {
+				fatalIf(os.MkdirAll(filepath.Dir(path), 0700))
+				fatalIf(ioutil.WriteFile(path, []byte(t.Template()), 0600))
+			}
+
+			// the editor may have arguments of its own, so let the shell
+			// split it
+			run := exec.Command("sh", "-c", editor()+` "$1"`, "sh", path)
+			run.Stdin = os.Stdin
+			run.Stdout = os.Stdout
+			run.Stderr = os.Stderr
+			fatalIf(run.Run())
+
+			// catch mistakes now rather than the next time the type is shown
+			text, _, e := template.Lookup(t)
+			fatalIf(e)
+			if _, e := template.NewTemplate().Parse(text); e != nil {
+				fatalIf(validationError("%s: %s", path, e))
+			}
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/kosh/template"
+)
+
+// defaultPager is used when $PAGER isn't set. -R passes colours through, -F
+// quits if the output fits on one screen and -X leaves it on the screen.
+const defaultPager = "less -FRX"
+
+// terminalSize returns the number of rows and columns of the controlling
+// terminal. $LINES and $COLUMNS take precedence. Zero is returned for any
+// dimension that can't be determined.
+func terminalSize() (rows, cols int) {
+	rows, _ = strconv.Atoi(os.Getenv("LINES"))
+	cols, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	if rows > 0 && cols > 0 {
+		return rows, cols
+	}
+
+	tty, e := os.Open("/dev/tty")
+	if e != nil {
+		return rows, cols
+	}
+	defer tty.Close()
+
+	stty := exec.Command("stty", "size")
+	stty.Stdin = tty
+	out, e := stty.Output()
+	if e != nil {
+		return rows, cols
+	}
+
+	var r, c int
+	if _, e := fmt.Sscan(string(out), &r, &c); e != nil {
+		return rows, cols
+	}
+	if rows <= 0 {
+		rows = r
+	}
+	if cols <= 0 {
+		cols = c
+	}
+	return rows, cols
+}
+
+// colorEnabled decides whether output is coloured from the --color mode,
+// whether STDOUT is a terminal and the NO_COLOR convention
+// (https://no-color.org)
+func colorEnabled(mode string, terminal bool) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto", "":
+		return terminal && os.Getenv("NO_COLOR") == "", nil
+	}
+	return false, usageError("--color must be one of auto, always, never: got '%s'", mode)
+}
+
+// colorCell colours the cells of the health and status columns of a table
+func colorCell(header, cell string) string {
+	switch header {
+	case "Health", "Status":
+		return template.Status(cell)
+	}
+	return cell
+}
+
+// page writes s to STDOUT, through $PAGER if it is longer than the terminal
+func (c Config) page(s string) {
+	if c.height <= 0 || strings.Count(s, "\n") < c.height {
+		fmt.Print(s)
+		return
+	}
+
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = defaultPager
+	}
+
+	run := exec.Command("sh", "-c", pager)
+	run.Stdin = strings.NewReader(s)
+	run.Stdout = os.Stdout
+	run.Stderr = os.Stderr
+	if e := run.Run(); e != nil {
+		c.Debug(fmt.Sprintf("pager '%s' failed: %s", pager, e))
+		if _, ok := e.(*exec.ExitError); !ok {
+			fmt.Print(s)
+		}
+	}
+}
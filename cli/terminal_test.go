@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColorEnabled(t *testing.T) {
+	defer os.Setenv("NO_COLOR", os.Getenv("NO_COLOR"))
+	os.Unsetenv("NO_COLOR")
+
+	tests := []struct {
+		mode     string
+		terminal bool
+		expected bool
+	}{
+		{"auto", true, true},
+		{"auto", false, false},
+		{"always", false, true},
+		{"never", true, false},
+	}
+	for _, test := range tests {
+		enabled, e := colorEnabled(test.mode, test.terminal)
+		assert.Nil(t, e)
+		assert.Equal(t, test.expected, enabled, test)
+	}
+
+	os.Setenv("NO_COLOR", "1")
+	enabled, _ := colorEnabled("auto", true)
+	assert.False(t, enabled)
+	enabled, _ = colorEnabled("always", true)
+	assert.True(t, enabled)
+
+	_, e := colorEnabled("sometimes", true)
+	assert.Equal(t, ExitUsage, exitCode(e))
+}
@@ -1,9 +1,6 @@
 package cli
 
 import (
-	"errors"
-	"fmt"
-
 	cli "github.com/jawher/mow.cli"
 	"github.com/joyent/kosh/conch"
 	"github.com/joyent/kosh/conch/types"
@@ -41,8 +38,8 @@ func tokensCmd(cmd *cli.Cmd) {
 		cmd.Action = func() { display(conch.GetCurrentUserTokens()) }
 	})
 
-	cmd.Command("create new add", "Get the settings for the current user", func(cmd *cli.Cmd) {
-		name := cmd.StringArg("NAME", "", "The string name of a setting")
+	cmd.Command("create new add", "Create a new token for the current user", func(cmd *cli.Cmd) {
+		name := cmd.StringArg("NAME", "", "The name of the new token")
 		user := cmd.StringOpt("user u", "", "User name to use for authentication")
 		pass := cmd.StringOpt("pass p", "", "Password to use for authentication")
 		cmd.Action = func() {
@@ -51,7 +48,7 @@ func tokensCmd(cmd *cli.Cmd) {
 				if e != nil {
 					fatalIf(e)
 				}
-				config.Debug(fmt.Sprintf("%+v", loginToken))
+				config.Debug(loginToken)
 				conch = conch.Authorization("Bearer " + loginToken.JwtToken)
 			}
 			display(conch.CreateCurrentUserToken(types.NewUserTokenRequest{Name: *name}))
@@ -75,7 +72,7 @@ func tokenCmd(cmd *cli.Cmd) {
 
 		var e error
 		if name == nil {
-			fatalIf(errors.New("must provide a valid token name"))
+			fatalIf(usageError("must provide a valid token name"))
 		}
 		token, e = conch.GetCurrentUserTokenByName(*name)
 		if e != nil {
@@ -89,9 +86,9 @@ func tokenCmd(cmd *cli.Cmd) {
 		cmd.Action = func() { display(token, nil) }
 	})
 
-	cmd.Command("delete rm", "display the user token information", func(cmd *cli.Cmd) {
+	cmd.Command("delete rm", "remove the token", func(cmd *cli.Cmd) {
 		cmd.Action = func() {
-			conch.DeleteCurrentUserToken(token.Name)
+			fatalIf(conch.DeleteCurrentUserToken(token.Name))
 			display(conch.GetCurrentUserTokens())
 		}
 	})
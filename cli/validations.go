@@ -1,8 +1,6 @@
 package cli
 
 import (
-	"errors"
-
 	cli "github.com/jawher/mow.cli"
 	"github.com/joyent/kosh/conch"
 	"github.com/joyent/kosh/conch/types"
@@ -35,7 +33,7 @@ func validationCmd(cmd *cli.Cmd) {
 			fatalIf(e)
 
 			if (plan == types.ValidationPlan{}) {
-				fatalIf(errors.New("could not find the validation plan"))
+				fatalIf(notFoundError("could not find the validation plan"))
 			}
 		}
 
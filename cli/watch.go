@@ -0,0 +1,348 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/tables"
+)
+
+const defaultWatchInterval = "2s"
+
+const (
+	ansiReset       = "\033[0m"
+	ansiRed         = "\033[31m"
+	ansiGreen       = "\033[32m"
+	ansiYellow      = "\033[33m"
+	ansiClearScreen = "\033[H\033[2J"
+)
+
+// normalizeWatchArgs rewrites --watch followed by an interval into
+// --watch=<interval>, and a bare --watch into --watch=<default interval>, so
+// that mow.cli, which has no notion of an option with an optional value, can
+// parse it
+func normalizeWatchArgs(args []string) []string {
+	normalized := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			normalized = append(normalized, args[i:]...)
+			break
+		}
+		if a == "--watch" {
+			interval := defaultWatchInterval
+			if i+1 < len(args) {
+				if _, e := parseWatchInterval(args[i+1]); e == nil {
+					interval = args[i+1]
+					i++
+				}
+			}
+			a = "--watch=" + interval
+		}
+		normalized = append(normalized, a)
+	}
+	return normalized
+}
+
+// parseWatchInterval accepts either a Go duration ("500ms", "1m") or a plain
+// number of seconds, like watch(1)
+func parseWatchInterval(s string) (time.Duration, error) {
+	if n, e := strconv.Atoi(s); e == nil {
+		s = fmt.Sprintf("%ds", n)
+	}
+	d, e := time.ParseDuration(s)
+	if e != nil {
+		return 0, fmt.Errorf("invalid --watch interval '%s': %s", s, e)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("--watch interval must be greater than zero")
+	}
+	return d, nil
+}
+
+// watchCapture collects whatever a command hands to its Renderer so that the
+// watch loop can compare it against the previous poll
+type watchCapture struct {
+	data interface{}
+	err  error
+	seen bool
+}
+
+func (w *watchCapture) display(i interface{}, e error) {
+	w.data, w.err, w.seen = i, e, true
+}
+
+// watchFailure is what fatalIf panics with under --watch, so that an error
+// stops a single run of the command rather than the whole watch
+type watchFailure struct {
+	err error
+}
+
+// runWatched runs the app once, catching an error the command fails with
+// under --watch and handing it to the watch capture
+func runWatched(app *cli.Cli, args []string) error {
+	defer func() {
+		if p := recover(); p != nil {
+			failure, ok := p.(watchFailure)
+			if !ok {
+				panic(p)
+			}
+			config.watch.display(nil, failure.err)
+		}
+	}()
+	return app.Run(args)
+}
+
+// watchEvent is a single change between two polls, used when the output
+// isn't a terminal
+type watchEvent struct {
+	Time     time.Time         `json:"time"`
+	Event    string            `json:"event"`
+	ID       string            `json:"id,omitempty"`
+	Row      map[string]string `json:"row,omitempty"`
+	Previous map[string]string `json:"previous,omitempty"`
+	Text     string            `json:"text,omitempty"`
+}
+
+type watcher struct {
+	config   Config
+	out      io.Writer
+	terminal bool
+	command  string
+
+	polled        bool
+	previous      map[string][]string
+	previousOrder []string
+	previousText  string
+}
+
+func newWatcher(c Config, out io.Writer, terminal bool, args []string) *watcher {
+	return &watcher{
+		config:   c,
+		out:      out,
+		terminal: terminal,
+		command:  strings.Join(append([]string{"kosh"}, args[1:]...), " "),
+	}
+}
+
+// poll compares the captured output of the latest run with the previous one
+// and either redraws the screen or emits change events
+func (w *watcher) poll(capture *watchCapture) {
+	if !capture.seen {
+		return
+	}
+	now := time.Now()
+
+	if capture.err != nil {
+		if w.terminal {
+			w.redraw(now, capture.err.Error())
+			return
+		}
+		w.emit(watchEvent{Time: now, Event: "error", Text: capture.err.Error()})
+		return
+	}
+
+	if list, ok := capture.data.(tables.Tabulable); ok {
+		w.pollTable(now, list)
+	} else {
+		w.pollText(now, capture.data)
+	}
+	w.polled = true
+}
+
+func (w *watcher) pollTable(now time.Time, list tables.Tabulable) {
+	opts, e := w.config.TableOptions()
+	fatalIf(e)
+
+	headers, rows, e := tables.Rows(list, opts)
+	fatalIf(e)
+
+	id := tables.IdentityColumn(list)
+
+	current := make(map[string][]string, len(rows))
+	order := make([]string, 0, len(rows))
+	status := make([]string, 0, len(rows))
+	events := []watchEvent{}
+
+	for _, row := range rows {
+		key := row[id]
+		current[key] = row
+		order = append(order, key)
+
+		prev, ok := w.previous[key]
+		switch {
+		case !ok:
+			status = append(status, "added")
+			events = append(events, watchEvent{Time: now, Event: "added", ID: key, Row: rowMap(headers, row)})
+		case !equalRows(prev, row):
+			status = append(status, "changed")
+			events = append(events, watchEvent{
+				Time:     now,
+				Event:    "changed",
+				ID:       key,
+				Row:      rowMap(headers, row),
+				Previous: rowMap(headers, prev),
+			})
+		default:
+			status = append(status, "")
+		}
+	}
+
+	for _, key := range w.previousOrder {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		rows = append(rows, w.previous[key])
+		status = append(status, "removed")
+		events = append(events, watchEvent{Time: now, Event: "removed", ID: key, Previous: rowMap(headers, w.previous[key])})
+	}
+
+	// on the very first poll everything is "added", which is only useful as
+	// a baseline for the event stream
+	if !w.polled {
+		for i := range status {
+			status[i] = ""
+		}
+	}
+
+	w.previous = current
+	w.previousOrder = order
+
+	if !w.terminal {
+		for _, event := range events {
+			w.emit(event)
+		}
+		return
+	}
+
+	headers, rows, e = tables.SelectColumns(headers, rows, opts.Columns)
+	fatalIf(e)
+
+	rendered := strings.Split(strings.TrimRight(tables.RenderRows(headers, rows, opts), "\n"), "\n")
+	offset := 0
+	if !opts.NoHeaders {
+		offset = 2 // the header row and the separator below it
+	}
+	for i, s := range status {
+		if offset+i < len(rendered) {
+			rendered[offset+i] = w.highlight(s, rendered[offset+i])
+		}
+	}
+	w.redraw(now, strings.Join(rendered, "\n"))
+}
+
+func (w *watcher) pollText(now time.Time, data interface{}) {
+	buf := &strings.Builder{}
+	c := w.config
+	c.watch = nil
+	c.RenderTo(buf)(data, nil)
+	text := buf.String()
+
+	changed := w.polled && text != w.previousText
+	w.previousText = text
+
+	if !w.terminal {
+		if !w.polled || changed {
+			w.emit(watchEvent{Time: now, Event: "changed", Text: text})
+		}
+		return
+	}
+
+	if changed {
+		text = w.highlight("changed", text)
+	}
+	w.redraw(now, text)
+}
+
+func (w *watcher) redraw(now time.Time, body string) {
+	fmt.Fprint(w.out, ansiClearScreen)
+	fmt.Fprintf(
+		w.out,
+		"Every %s: %s    %s\n\n",
+		w.config.WatchInterval,
+		w.command,
+		now.Format(time.RFC1123),
+	)
+	fmt.Fprintln(w.out, body)
+}
+
+func (w *watcher) emit(event watchEvent) {
+	if w.config.OutputJSON {
+		fmt.Fprintln(w.out, renderJSON(event))
+		return
+	}
+
+	line := fmt.Sprintf("%s %s", event.Time.Format(time.RFC3339), event.Event)
+	if event.ID != "" {
+		line = fmt.Sprintf("%s %s", line, event.ID)
+	}
+
+	switch event.Event {
+	case "changed":
+		if event.Text != "" {
+			line = fmt.Sprintf("%s\n%s", line, event.Text)
+			break
+		}
+		for _, k := range changedKeys(event.Previous, event.Row) {
+			line = fmt.Sprintf("%s %s: %q -> %q", line, k, event.Previous[k], event.Row[k])
+		}
+	case "error":
+		line = fmt.Sprintf("%s %s", line, event.Text)
+	}
+	fmt.Fprintln(w.out, line)
+}
+
+func (w *watcher) highlight(status, s string) string {
+	if !w.config.colorize {
+		return s
+	}
+	switch status {
+	case "added":
+		return ansiGreen + s + ansiReset
+	case "removed":
+		return ansiRed + s + ansiReset
+	case "changed":
+		return ansiYellow + s + ansiReset
+	}
+	return s
+}
+
+func rowMap(headers, row []string) map[string]string {
+	m := make(map[string]string, len(headers))
+	for i, h := range headers {
+		if i < len(row) {
+			m[h] = row[i]
+		}
+	}
+	return m
+}
+
+func equalRows(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// changedKeys returns the keys whose values differ between the two maps, in
+// a stable order
+func changedKeys(a, b map[string]string) []string {
+	keys := []string{}
+	for k, v := range b {
+		if a[k] != v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
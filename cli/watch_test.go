@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	cli "github.com/jawher/mow.cli"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeWatchArgs(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"kosh", "--watch=" + defaultWatchInterval, "relays"},
+		normalizeWatchArgs([]string{"kosh", "--watch", "relays"}),
+	)
+	assert.Equal(
+		t,
+		[]string{"kosh", "--watch=5s", "relays"},
+		normalizeWatchArgs([]string{"kosh", "--watch=5s", "relays"}),
+	)
+	assert.Equal(
+		t,
+		[]string{"kosh", "--watch=5s", "relays"},
+		normalizeWatchArgs([]string{"kosh", "--watch", "5s", "relays"}),
+	)
+	assert.Equal(
+		t,
+		[]string{"kosh", "--watch=10", "relays"},
+		normalizeWatchArgs([]string{"kosh", "--watch", "10", "relays"}),
+	)
+	assert.Equal(
+		t,
+		[]string{"kosh", "api", "--", "--watch", "5s"},
+		normalizeWatchArgs([]string{"kosh", "api", "--", "--watch", "5s"}),
+	)
+}
+
+func TestRunWatchedKeepsWatchingAfterAnError(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+	config = NewConfig("test", "test")
+	config.watch = &watchCapture{}
+
+	app := cli.App("kosh", "")
+	app.Action = func() { fatalIf(errors.New("connection refused")) }
+	assert.Nil(t, runWatched(app, []string{"kosh"}))
+	assert.True(t, config.watch.seen)
+	assert.EqualError(t, config.watch.err, "connection refused")
+}
+
+func TestParseWatchInterval(t *testing.T) {
+	d, e := parseWatchInterval("5")
+	assert.Nil(t, e)
+	assert.Equal(t, "5s", d.String())
+
+	d, e = parseWatchInterval("500ms")
+	assert.Nil(t, e)
+	assert.Equal(t, "500ms", d.String())
+
+	_, e = parseWatchInterval("0")
+	assert.NotNil(t, e)
+
+	_, e = parseWatchInterval("soon")
+	assert.NotNil(t, e)
+}
+
+func TestWatcherEvents(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := newWatcher(NewConfig("test", "test"), buf, false, []string{"kosh", "rack", "foo", "assignments"})
+
+	poll := func(ra types.RackAssignments) []string {
+		buf.Reset()
+		w.poll(&watchCapture{data: ra, seen: true})
+		lines := []string{}
+		for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if l != "" {
+				lines = append(lines, strings.SplitN(l, " ", 2)[1])
+			}
+		}
+		return lines
+	}
+
+	assert.Equal(t, []string{"added 3", "added 1"}, poll(types.RackAssignments{
+		{DeviceSerialNumber: "a", RackUnitStart: 1},
+		{DeviceSerialNumber: "b", RackUnitStart: 3},
+	}))
+
+	assert.Empty(t, poll(types.RackAssignments{
+		{DeviceSerialNumber: "a", RackUnitStart: 1},
+		{DeviceSerialNumber: "b", RackUnitStart: 3},
+	}))
+
+	assert.Equal(t, []string{
+		"added 5",
+		`changed 3 Device Serial: "b" -> "c"`,
+		"removed 1",
+	}, poll(types.RackAssignments{
+		{DeviceSerialNumber: "c", RackUnitStart: 3},
+		{DeviceSerialNumber: "d", RackUnitStart: 5},
+	}))
+}
@@ -0,0 +1,290 @@
+/*
+Package collect gathers a device report from a Linux host, reading sysfs,
+procfs and the DMI tables. Every path is read relative to a root directory, so
+that a report can be collected from a copy of those trees as well as from the
+running host.
+*/
+package collect
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/joyent/kosh/conch/types"
+)
+
+// Collector reads a device report from the sysfs, procfs and DMI trees under
+// Root
+type Collector struct {
+	// Root is the directory /sys and /proc are found in, / for the running
+	// host
+	Root string
+	// Now returns the current time, used to work out when the host booted
+	Now func() time.Time
+}
+
+// New returns a Collector reading from the given root directory
+func New(root string) *Collector {
+	if root == "" {
+		root = "/"
+	}
+	return &Collector{Root: root, Now: time.Now}
+}
+
+// path returns the location of a file under the collector's root
+func (c *Collector) path(elem ...string) string {
+	return filepath.Join(append([]string{c.Root}, elem...)...)
+}
+
+// read returns the trimmed contents of a file under the root, or an empty
+// string if it can't be read
+func (c *Collector) read(elem ...string) string {
+	b, e := ioutil.ReadFile(c.path(elem...))
+	if e != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// readInt returns the integer in a file under the root, or 0
+func (c *Collector) readInt(elem ...string) int {
+	n, _ := strconv.Atoi(c.read(elem...))
+	return n
+}
+
+// exists reports whether a path exists under the root, following symlinks
+func (c *Collector) exists(elem ...string) bool {
+	_, e := os.Stat(c.path(elem...))
+	return e == nil
+}
+
+// list returns the sorted names of the entries of a directory under the root
+func (c *Collector) list(elem ...string) []string {
+	infos, e := ioutil.ReadDir(c.path(elem...))
+	if e != nil {
+		return nil
+	}
+	names := []string{}
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Report collects a device report. Only the serial number is required; any
+// other part that can't be read is left out, for the API's schema and
+// validations to catch.
+func (c *Collector) Report() (types.DeviceReport, error) {
+	report := types.DeviceReport{
+		DeviceType:   "server",
+		SerialNumber: types.DeviceSerialNumber(c.read("sys/class/dmi/id/product_serial")),
+		ProductName:  c.read("sys/class/dmi/id/product_name"),
+		BiosVersion:  c.read("sys/class/dmi/id/bios_version"),
+	}
+	if report.SerialNumber == "" {
+		return report, fmt.Errorf("unable to read the serial number from %s (reading it usually needs root)", c.path("sys/class/dmi/id/product_serial"))
+	}
+
+	if id, e := uuid.FromString(c.read("sys/class/dmi/id/product_uuid")); e == nil {
+		report.SystemUUID = types.UUID{UUID: id}
+	}
+
+	report.Cpus = c.cpus()
+	report.Dimms = c.dimms()
+	report.Disks = c.disks()
+	report.Interfaces = c.interfaces()
+
+	if hostname := c.read("proc/sys/kernel/hostname"); hostname != "" {
+		report.Os = &types.Os{Hostname: hostname}
+	}
+	if fields := strings.Fields(c.read("proc/uptime")); len(fields) > 0 {
+		if seconds, e := strconv.ParseFloat(fields[0], 64); e == nil {
+			boot := c.Now().Add(-time.Duration(seconds * float64(time.Second)))
+			report.UptimeSince = boot.UTC().Truncate(time.Second).Format(time.RFC3339)
+		}
+	}
+	return report, nil
+}
+
+// cpus reads /proc/cpuinfo, returning a CpusItem for each socket
+func (c *Collector) cpus() []types.CpusItem {
+	f, e := os.Open(c.path("proc/cpuinfo"))
+	if e != nil {
+		return nil
+	}
+	defer f.Close()
+
+	sockets := map[int]types.CpusItem{}
+	processor := map[string]string{}
+	flush := func() {
+		if len(processor) == 0 {
+			return
+		}
+		id, _ := strconv.Atoi(processor["physical id"])
+		if _, ok := sockets[id]; !ok {
+			cores, _ := strconv.Atoi(processor["cpu cores"])
+			threads, _ := strconv.Atoi(processor["siblings"])
+			sockets[id] = types.CpusItem{
+				Socket:  types.NewIntOrStringyInt(id),
+				Vendor:  processor["vendor_id"],
+				Model:   processor["model name"],
+				Cores:   types.NewIntOrStringyInt(cores),
+				Threads: types.NewIntOrStringyInt(threads),
+			}
+		}
+		processor = map[string]string{}
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		bits := strings.SplitN(line, ":", 2)
+		if len(bits) == 2 {
+			processor[strings.TrimSpace(bits[0])] = strings.TrimSpace(bits[1])
+		}
+	}
+	flush()
+
+	ids := []int{}
+	for id := range sockets {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	cpus := []types.CpusItem{}
+	for _, id := range ids {
+		cpus = append(cpus, sockets[id])
+	}
+	return cpus
+}
+
+// dimms reads the installed memory devices from the raw DMI type 17 entries
+func (c *Collector) dimms() []types.Dimm {
+	dimms := []types.Dimm{}
+	for _, name := range c.list("sys/firmware/dmi/entries") {
+		if !strings.HasPrefix(name, "17-") {
+			continue
+		}
+		raw, e := ioutil.ReadFile(c.path("sys/firmware/dmi/entries", name, "raw"))
+		if e != nil {
+			continue
+		}
+		device, ok := parseMemoryDevice(raw)
+		if !ok || device.SizeMB == 0 {
+			continue
+		}
+		dimm := types.Dimm{
+			MemoryLocator: device.Locator,
+			MemorySize:    types.NewIntOrStringyInt(device.SizeMB / 1024),
+		}
+		if device.Serial != "" {
+			dimm.MemorySerialNumber = device.Serial
+		}
+		dimms = append(dimms, dimm)
+	}
+	return dimms
+}
+
+// diskSkipped reports whether a block device isn't a physical disk worth
+// reporting: virtual devices have no device link and removable ones are
+// usually USB sticks or optical drives
+func (c *Collector) diskSkipped(name string) bool {
+	return !c.exists("sys/block", name, "device") || c.read("sys/block", name, "removable") == "1"
+}
+
+// diskSerial reads a disk's serial number, from the device itself or its
+// SCSI unit serial number VPD page
+func (c *Collector) diskSerial(name string) string {
+	if serial := c.read("sys/block", name, "device/serial"); serial != "" {
+		return serial
+	}
+	page, e := ioutil.ReadFile(c.path("sys/block", name, "device/vpd_pg80"))
+	if e != nil || len(page) < 4 {
+		return ""
+	}
+	end := 4 + int(page[3])
+	if end > len(page) {
+		end = len(page)
+	}
+	return strings.TrimSpace(strings.Trim(string(page[4:end]), "\x00"))
+}
+
+// disks reads the physical disks from /sys/block, keyed by serial number or,
+// failing that, the device name
+func (c *Collector) disks() map[string]types.Disk {
+	disks := map[string]types.Disk{}
+	for _, name := range c.list("sys/block") {
+		if c.diskSkipped(name) {
+			continue
+		}
+
+		blockSize := c.readInt("sys/block", name, "queue/logical_block_size")
+		disk := types.Disk{
+			BlockSz: blockSize,
+			// /sys/block/*/size is always in 512 byte sectors
+			Size:   int(int64(c.readInt("sys/block", name, "size")) * 512 / types.DiskSizeUnit),
+			Model:  c.read("sys/block", name, "device/model"),
+			Vendor: c.read("sys/block", name, "device/vendor"),
+		}
+
+		media := "SSD"
+		if c.read("sys/block", name, "queue/rotational") == "1" {
+			media = "HDD"
+		}
+		switch {
+		case strings.HasPrefix(name, "nvme"):
+			disk.Transport = "nvme"
+			disk.Firmware = c.read("sys/block", name, "device/firmware_rev")
+		case disk.Vendor == "ATA":
+			disk.Transport = "sata"
+			disk.Firmware = c.read("sys/block", name, "device/rev")
+		default:
+			disk.Transport = "sas"
+			disk.Firmware = c.read("sys/block", name, "device/rev")
+		}
+		disk.DriveType = strings.ToUpper(disk.Transport) + "_" + media
+
+		serial := c.diskSerial(name)
+		if serial == "" {
+			serial = name
+		}
+		disks[serial] = disk
+	}
+	return disks
+}
+
+// interfaces reads the physical network interfaces from /sys/class/net.
+// Vendor and product are the PCI IDs of the network card.
+func (c *Collector) interfaces() map[string]types.Interface {
+	interfaces := map[string]types.Interface{}
+	for _, name := range c.list("sys/class/net") {
+		if !c.exists("sys/class/net", name, "device") {
+			continue
+		}
+		iface := types.Interface{
+			Mac:     types.Macaddr(c.read("sys/class/net", name, "address")),
+			Vendor:  c.read("sys/class/net", name, "device/vendor"),
+			Product: c.read("sys/class/net", name, "device/device"),
+		}
+		if mtu := c.readInt("sys/class/net", name, "mtu"); mtu > 0 {
+			iface.Mtu = types.NewIntOrStringyInt(mtu)
+		}
+		if state := c.read("sys/class/net", name, "operstate"); state != "" {
+			iface.State = state
+		}
+		interfaces[name] = iface
+	}
+	return interfaces
+}
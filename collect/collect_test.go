@@ -0,0 +1,91 @@
+package collect
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var n = types.NewIntOrStringyInt
+
+func TestReport(t *testing.T) {
+	c := New("fixtures/linux")
+	c.Now = func() time.Time { return time.Date(2020, 11, 2, 12, 0, 0, 0, time.UTC) }
+
+	report, e := c.Report()
+	assert.Nil(t, e)
+
+	assert.Equal(t, types.DeviceSerialNumber("S1234567"), report.SerialNumber)
+	assert.Equal(t, "4c4c4544-0042-3510-8051-b3c04f4e4d32", report.SystemUUID.String())
+	assert.Equal(t, "Joyent-Compute-Platform-3301", report.ProductName)
+	assert.Equal(t, "2.8.2", report.BiosVersion)
+	assert.Equal(t, "server", report.DeviceType)
+	assert.Equal(t, &types.Os{Hostname: "host1.example.com"}, report.Os)
+	assert.Equal(t, "2020-11-02T10:59:59Z", report.UptimeSince)
+
+	assert.Equal(t, []types.CpusItem{
+		{Socket: n(0), Vendor: "GenuineIntel", Model: "Intel(R) Xeon(R) Gold 6140 CPU @ 2.30GHz", Cores: n(1), Threads: n(2)},
+		{Socket: n(1), Vendor: "GenuineIntel", Model: "Intel(R) Xeon(R) Gold 6140 CPU @ 2.30GHz", Cores: n(1), Threads: n(2)},
+	}, report.Cpus)
+
+	assert.Equal(t, []types.Dimm{
+		{MemoryLocator: "P1-DIMMA1", MemorySerialNumber: "15C7A1B2", MemorySize: n(16)},
+		{MemoryLocator: "P2-DIMMA1", MemorySerialNumber: "15C7A1B3", MemorySize: n(64)},
+	}, report.Dimms)
+
+	assert.Equal(t, map[string]types.Disk{
+		"S45PNA0M123456": {
+			BlockSz:   512,
+			DriveType: "SATA_SSD",
+			Firmware:  "HXT7",
+			Model:     "SAMSUNG MZ7LH480",
+			Size:      457862,
+			Transport: "sata",
+			Vendor:    "ATA",
+		},
+		"PHLJ912345672P0BGN": {
+			BlockSz:   4096,
+			DriveType: "NVME_SSD",
+			Firmware:  "VDV10131",
+			Model:     "INTEL SSDPE2KX020T8",
+			Size:      1831420,
+			Transport: "nvme",
+		},
+	}, report.Disks)
+
+	assert.Equal(t, map[string]types.Interface{
+		"eth0": {Mac: "00:1b:21:aa:bb:cc", Mtu: n(9000), State: "up", Vendor: "0x8086", Product: "0x1572"},
+		"eth1": {Mac: "00:1b:21:aa:bb:cd", Mtu: n(1500), State: "down", Vendor: "0x8086", Product: "0x1572"},
+	}, report.Interfaces)
+
+	_, e = json.Marshal(report)
+	assert.Nil(t, e)
+}
+
+func TestReportWithoutSerial(t *testing.T) {
+	_, e := New(t.TempDir()).Report()
+	assert.NotNil(t, e)
+}
+
+func TestParseMemoryDevice(t *testing.T) {
+	raw := make([]byte, 0x1C)
+	raw[0], raw[1] = 17, 0x1C
+	raw[0x0C], raw[0x0D] = 0x00, 0x82 // 512KB, in kilobytes
+	raw[0x10] = 1
+	raw = append(raw, []byte("DIMM_A1\x00\x00")...)
+
+	device, ok := parseMemoryDevice(raw)
+	assert.True(t, ok)
+	assert.Equal(t, memoryDevice{Locator: "DIMM_A1", SizeMB: 0}, device)
+
+	raw[0x0C], raw[0x0D] = 0x00, 0x40 // 16384MB
+	device, ok = parseMemoryDevice(raw)
+	assert.True(t, ok)
+	assert.Equal(t, 16384, device.SizeMB)
+
+	_, ok = parseMemoryDevice([]byte{16, 4, 0, 0})
+	assert.False(t, ok)
+}
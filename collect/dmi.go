@@ -0,0 +1,80 @@
+package collect
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// memoryDevice is the part of an SMBIOS type 17 (Memory Device) structure a
+// device report needs
+type memoryDevice struct {
+	Locator string
+	Serial  string
+	SizeMB  int
+}
+
+// Offsets of the fields of an SMBIOS type 17 structure, from the DMTF SMBIOS
+// reference specification
+const (
+	memoryDeviceType     = 17
+	memorySizeOffset     = 0x0C
+	memoryLocatorOffset  = 0x10
+	memorySerialOffset   = 0x18
+	memoryExtSizeOffset  = 0x1C
+	memorySizeUnknown    = 0xFFFF
+	memorySizeExtended   = 0x7FFF
+	memorySizeInKilobyte = 0x8000
+)
+
+// smbiosStrings returns the strings that follow the formatted area of an
+// SMBIOS structure
+func smbiosStrings(raw []byte) []string {
+	if len(raw) < 2 || int(raw[1]) > len(raw) {
+		return nil
+	}
+	area := raw[raw[1]:]
+	if end := strings.Index(string(area), "\x00\x00"); end >= 0 {
+		area = area[:end]
+	}
+	if len(area) == 0 {
+		return nil
+	}
+	return strings.Split(string(area), "\x00")
+}
+
+// parseMemoryDevice parses a raw SMBIOS type 17 structure, as found in
+// /sys/firmware/dmi/entries/17-*/raw. A size of 0 means the slot is empty.
+func parseMemoryDevice(raw []byte) (memoryDevice, bool) {
+	device := memoryDevice{}
+	if len(raw) < memoryLocatorOffset+1 || raw[0] != memoryDeviceType || int(raw[1]) <= memoryLocatorOffset {
+		return device, false
+	}
+	length := int(raw[1])
+	strs := smbiosStrings(raw)
+	str := func(offset int) string {
+		if offset >= length {
+			return ""
+		}
+		i := int(raw[offset])
+		if i < 1 || i > len(strs) {
+			return ""
+		}
+		return strings.TrimSpace(strs[i-1])
+	}
+
+	device.Locator = str(memoryLocatorOffset)
+	device.Serial = str(memorySerialOffset)
+
+	switch size := binary.LittleEndian.Uint16(raw[memorySizeOffset:]); {
+	case size == memorySizeUnknown:
+	case size == memorySizeExtended:
+		if length >= memoryExtSizeOffset+4 {
+			device.SizeMB = int(binary.LittleEndian.Uint32(raw[memoryExtSizeOffset:]) & 0x7FFFFFFF)
+		}
+	case size&memorySizeInKilobyte != 0:
+		device.SizeMB = int(size&^memorySizeInKilobyte) / 1024
+	default:
+		device.SizeMB = int(size)
+	}
+	return device, true
+}
@@ -0,0 +1,69 @@
+package conch
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Raw makes a request with the given method to a path relative to the API
+// base URL, which may include a query string. The headers are added to the
+// client's own and body, if not nil, is sent as it is. It returns the
+// response and its body, undecoded. If the API responds with an error status
+// the error is an *HTTPError, which also holds the body.
+func (c *Client) Raw(method, path string, header http.Header, body io.Reader) (*http.Response, []byte, error) {
+	c = c.New()
+	path = strings.TrimPrefix(path, "/")
+
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
+		c.Sling.Get(path)
+	case http.MethodHead:
+		c.Sling.Head(path)
+	case http.MethodPost:
+		c.Sling.Post(path)
+	case http.MethodPut:
+		c.Sling.Put(path)
+	case http.MethodPatch:
+		c.Sling.Patch(path)
+	case http.MethodDelete:
+		c.Sling.Delete(path)
+	default:
+		return nil, nil, fmt.Errorf("unsupported HTTP method '%s'", method)
+	}
+
+	for k, values := range header {
+		for _, v := range values {
+			c.Sling.Add(k, v)
+		}
+	}
+	if body != nil {
+		c.Sling.Body(body)
+	}
+
+	var raw []byte
+	res, e := c.do(&raw)
+	if httpErr, ok := e.(*HTTPError); ok {
+		raw = httpErr.Body
+	}
+	return res, raw, e
+}
+
+var linkNext = regexp.MustCompile(`<([^>]*)>\s*;[^,]*\brel="?next"?`)
+
+// NextPage returns the URL of the next page of results given in the Link
+// header of a paginated response, or an empty string if this is the last
+// page
+func NextPage(res *http.Response) string {
+	if res == nil {
+		return ""
+	}
+	for _, link := range res.Header.Values("Link") {
+		if m := linkNext.FindStringSubmatch(link); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package conch_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRaw(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"Not found"}`))
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("plain text"))
+		default:
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Header().Set("Link", `<`+"http://"+r.Host+`/next?page=2>; rel="next", </first>; rel="first"`)
+			w.Write([]byte(r.Method + " " + r.URL.RequestURI() + " " + r.Header.Get("X-Test") + " " + string(body)))
+		}
+	}))
+	defer ts.Close()
+
+	c := conch.New(conch.API(ts.URL))
+
+	res, body, e := c.Raw("post", "/device/x/links?a=1", http.Header{"X-Test": {"yes"}}, strings.NewReader(`{}`))
+	assert.Nil(t, e)
+	assert.Equal(t, "POST /device/x/links?a=1 yes {}", string(body))
+	assert.Equal(t, ts.URL+"/next?page=2", conch.NextPage(res))
+
+	_, body, e = c.Raw("GET", "text", nil, nil)
+	assert.Nil(t, e)
+	assert.Equal(t, "plain text", string(body))
+
+	_, body, e = c.Raw("GET", "/missing", nil, nil)
+	var httpErr *conch.HTTPError
+	assert.True(t, errors.As(e, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	assert.JSONEq(t, `{"error":"Not found"}`, string(body))
+
+	_, _, e = c.Raw("TRACE", "/", nil, nil)
+	assert.NotNil(t, e)
+
+	assert.Equal(t, "", conch.NextPage(&http.Response{Header: http.Header{}}))
+}
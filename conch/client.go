@@ -2,6 +2,7 @@ package conch
 
 import (
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -73,18 +74,35 @@ func Logger(logger logger.Interface) Option {
 	return func(c *Client) { c.Logger = logger }
 }
 
+// ShareInflight returns an Option that answers a GET from an identical one
+// already in flight, through the given Inflight
+func ShareInflight(inflight *Inflight) Option {
+	return func(c *Client) { c.Inflight = inflight }
+}
+
+// RecordStats returns an Option that records the timing, status and size of
+// every request in the given Stats
+func RecordStats(stats *Stats) Option {
+	return func(c *Client) { c.Stats = stats }
+}
+
 // Client is a struct that represnts the current Conch client.
 type Client struct {
-	Sling  *sling.Sling
-	Logger logger.Interface
+	Sling    *sling.Sling
+	Logger   logger.Interface
+	Stats    *Stats
+	Inflight *Inflight
 }
 
 // New performs a shallow clone of the current client and returns the
 // new instance
 func (c *Client) New() *Client {
-	s := c.Sling.New()
-	l := c.Logger
-	return &Client{s, l}
+	return &Client{
+		Sling:    c.Sling.New(),
+		Logger:   c.Logger,
+		Stats:    c.Stats,
+		Inflight: c.Inflight,
+	}
 }
 
 // UserAgent sets the client's User-Agent header in the request
@@ -347,6 +365,14 @@ func (c *Client) Post(data interface{}) *Client {
 	return c
 }
 
+// PostRaw sets the HTTP method to POST and sends the given JSON as the body
+// exactly as it is
+func (c *Client) PostRaw(body io.Reader) *Client {
+	c = c.New()
+	c.Sling.Post("").Body(body).Set("Content-Type", "application/json")
+	return c
+}
+
 // Put sets the HTTP method to PUT and sets the JSON body to the given data
 func (c *Client) Put(data interface{}) *Client {
 	c = c.New()
@@ -364,50 +390,171 @@ func (c *Client) Delete(data ...interface{}) *Client {
 	return c
 }
 
-// Send sends a HTTP request to the API server  without expecting a return data
-// structure. It returns the *http.Response and/or error from the request.
-func (c *Client) Send() (*http.Response, error) {
-	c.Logger.Debug("Send")
-	req, err := c.Sling.Request()
-	c.Logger.Info(fmt.Sprintf("URL: %v", req.URL))
-	c.Logger.Debug(req, err)
+// HTTPError is returned when the API server responds with an error status
+// code. Message holds the error string from the response body, if any, and
+// Body the response body itself.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	URL        string
+	Message    string
+	Body       []byte
+}
 
-	res, err := c.Sling.Do(req, nil, nil)
-	c.Logger.Debug(res, err)
-	if err != nil {
-		return res, err
+func (e *HTTPError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("http error: %v: %s", e.Status, e.Message)
 	}
+	return fmt.Sprintf("http error: %v", e.Status)
+}
 
-	if res.StatusCode >= 400 {
-		return res, fmt.Errorf("http error: %v", res.Status)
+// apiError is the structure of the body the API sends along with an error
+// status
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func newHTTPError(req *http.Request, res *http.Response, body apiError) *HTTPError {
+	return &HTTPError{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Message:    body.Error,
 	}
+}
 
-	return res, err
+// Send sends a HTTP request to the API server  without expecting a return data
+// structure. It returns the *http.Response and/or error from the request.
+func (c *Client) Send() (*http.Response, error) {
+	c.logger().Debug("Send")
+	return c.do(nil)
 }
 
 // Receive sends a HTTP request to the API server and decodes the results into
 // the provided structure structure. It returns the *http.Response and/or error
 // from the request.
 func (c *Client) Receive(data interface{}) (*http.Response, error) {
-	if c.Logger != nil {
-		c.Logger.Debug("Receive")
+	c.logger().Debug("Receive")
+	return c.do(data)
+}
+
+func (c *Client) logger() logger.Interface {
+	if c.Logger == nil {
+		return logger.NullLogger{}
 	}
+	return c.Logger
+}
+
+// do makes the request, decoding a successful response into data if it isn't
+// nil, and logs a record of the request at info level. If the client has an
+// Inflight, a GET identical to one already in flight waits for and shares its
+// response.
+func (c *Client) do(data interface{}) (*http.Response, error) {
 	req, err := c.Sling.Request()
-	if c.Logger != nil {
-		c.Logger.Info(fmt.Sprintf("URL: %v", req.URL))
-		c.Logger.Debug(req, err)
+	if err != nil {
+		return nil, err
+	}
+	c.logger().Debug(req)
+
+	var f *flight
+	if data != nil {
+		var leader bool
+		if f, leader = c.Inflight.join(req); !leader {
+			return c.share(req, f, data)
+		}
+	}
+	res, body, err := c.send(req, data)
+	c.Inflight.land(req, f, res, body, err)
+	return res, err
+}
+
+// share waits for a flight to land and decodes its response into data,
+// recording it as a cached request
+func (c *Client) share(req *http.Request, f *flight, data interface{}) (*http.Response, error) {
+	start := time.Now()
+	<-f.done
+	stat := RequestStat{
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Endpoint: endpoint(req.URL.Path),
+		Duration: time.Since(start),
+		Bytes:    int64(len(f.body)),
+		Cached:   true,
+	}
+	if f.res != nil {
+		stat.Status = f.res.StatusCode
+	}
+	if f.err != nil {
+		stat.Error = f.err.Error()
 	}
+	c.Stats.Record(stat)
+	c.logger().Info("request", logger.Fields{
+		"method":      stat.Method,
+		"url":         stat.URL,
+		"duration_ms": float64(stat.Duration.Microseconds()) / 1000,
+		"bytes":       stat.Bytes,
+		"status":      stat.Status,
+		"cached":      true,
+	})
+
+	if f.err != nil || len(f.body) == 0 {
+		return f.res, f.err
+	}
+	return f.res, decodeBody(f.body, data)
+}
+
+// send makes the request, returning the response body along with the
+// response, and records and logs it
+func (c *Client) send(req *http.Request, data interface{}) (*http.Response, []byte, error) {
+	log := c.logger()
+
+	stat := RequestStat{
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Endpoint: endpoint(req.URL.Path),
+	}
+
+	var body []byte
+	start := time.Now()
+	failure := apiError{}
+	res, err := c.Sling.New().
+		ResponseDecoder(capturingDecoder{&body}).
+		Do(req, data, &failure)
+	stat.Duration = time.Since(start)
+	log.Debug(res, err)
+
+	stat.Bytes = int64(len(body))
+	if res != nil {
+		stat.Status = res.StatusCode
+		if stat.Bytes == 0 && res.ContentLength > 0 {
+			stat.Bytes = res.ContentLength
+		}
+	}
+	if err != nil {
+		stat.Error = err.Error()
+	}
+	c.Stats.Record(stat)
 
-	res, err := c.Sling.ReceiveSuccess(data)
-	if c.Logger != nil {
-		c.Logger.Debug(res, err)
+	fields := logger.Fields{
+		"method":      stat.Method,
+		"url":         stat.URL,
+		"duration_ms": float64(stat.Duration.Microseconds()) / 1000,
+		"bytes":       stat.Bytes,
+	}
+	if res != nil {
+		fields["status"] = res.StatusCode
 	}
 	if err != nil {
-		return res, err
+		fields["error"] = err.Error()
 	}
+	log.Info("request", fields)
 
-	if res.StatusCode >= 400 {
-		return res, fmt.Errorf("http error: %v", res.Status)
+	if res != nil && res.StatusCode >= 400 {
+		e := newHTTPError(req, res, failure)
+		e.Body = body
+		return res, body, e
 	}
-	return res, err
+	return res, body, err
 }
@@ -2,8 +2,8 @@ package conch_test
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -22,16 +22,14 @@ func TestDeviceReportRoutes(t *testing.T) {
 			URL:    "/device_report/",
 			Method: "POST",
 			Do: func(c *conch.Client) {
-				j, _ := json.Marshal("{}")
-				_ = c.SendDeviceReport(bytes.NewBuffer(j))
+				_ = c.SendDeviceReport(bytes.NewBufferString("{}"))
 			},
 		},
 		{
-			URL:    "/device_report/",
+			URL:    "/device_report/?no_update_db=1",
 			Method: "POST",
 			Do: func(c *conch.Client) {
-				j, _ := json.Marshal("{}")
-				_ = c.ValidateDeviceReport(bytes.NewBuffer(j))
+				_, _ = c.ValidateDeviceReport(bytes.NewBufferString("{}"))
 			},
 		},
 		{
@@ -58,3 +56,18 @@ func TestDeviceReportRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateDeviceReportSendsTheReportAsItIs(t *testing.T) {
+	report := `{"serial_number": "S1", "cpus": [{"socket": "0"}], "unknown_field": 1.50}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, e := ioutil.ReadAll(r.Body)
+		assert.Nil(t, e)
+		assert.Equal(t, report, string(body))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		w.Write([]byte(`{"status": "pass", "results": []}`))
+	}))
+	defer ts.Close()
+
+	_, e := conch.New(conch.API(ts.URL)).ValidateDeviceReport(bytes.NewBufferString(report))
+	assert.Nil(t, e)
+}
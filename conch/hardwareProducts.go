@@ -23,8 +23,8 @@ func (c *Client) CreateHardwareProduct(product types.HardwareProductCreate) erro
 
 // ReadHardwareProduct takes an io reader and returns a HardwareProductCreate
 // struct suitable for CreateHardwareProduct
-func (c *Client) ReadHardwareProduct(r io.Reader) (create types.HardwareProductCreate) {
-	json.NewDecoder(r).Decode(&create)
+func (c *Client) ReadHardwareProduct(r io.Reader) (create types.HardwareProductCreate, e error) {
+	e = json.NewDecoder(r).Decode(&create)
 	return
 }
 
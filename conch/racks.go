@@ -55,8 +55,8 @@ func (c *Client) UpdateRackLayout(id types.UUID, layout types.RackLayoutUpdate)
 
 // ReadRackLayoutUpdate takes an io.Reader and returns a RackLayoutUpdate
 // struct suitable for UpdateRackLayout
-func (c *Client) ReadRackLayoutUpdate(r io.Reader) (update types.RackLayoutUpdate) {
-	json.NewDecoder(r).Decode(&update)
+func (c *Client) ReadRackLayoutUpdate(r io.Reader) (update types.RackLayoutUpdate, e error) {
+	e = json.NewDecoder(r).Decode(&update)
 	return
 }
 
@@ -76,8 +76,8 @@ func (c *Client) UpdateRackAssignments(id types.UUID, rack types.RackAssignmentU
 
 // ReadRackAssignmentUpdate takes an io reader and returns a RackAssignmentUpdates
 // struct suitable for UpdateRackAssignments
-func (c *Client) ReadRackAssignmentUpdate(r io.Reader) (update types.RackAssignmentUpdates) {
-	json.NewDecoder(r).Decode(&update)
+func (c *Client) ReadRackAssignmentUpdate(r io.Reader) (update types.RackAssignmentUpdates, e error) {
+	e = json.NewDecoder(r).Decode(&update)
 	return
 }
 
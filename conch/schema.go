@@ -10,3 +10,10 @@ func (c *Client) GetSchema(path string) (schema jsonschema.Schema, e error) {
 	_, e = c.Schema(path).Receive(&schema)
 	return
 }
+
+// GetSchemaJSON (GET /json_schema) retrieves the json-schema defined with the
+// given path as it is, undecoded
+func (c *Client) GetSchemaJSON(path string) (schema []byte, e error) {
+	_, e = c.Schema(path).Receive(&schema)
+	return
+}
@@ -0,0 +1,229 @@
+package conch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestStat records a single request made by the client
+type RequestStat struct {
+	Method   string        `json:"method"`
+	URL      string        `json:"url"`
+	Endpoint string        `json:"endpoint"`
+	Status   int           `json:"status,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	Bytes    int64         `json:"bytes"`
+	Cached   bool          `json:"cached"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Stats collects a RequestStat for every request made by the clients that
+// share it. It is safe for concurrent use.
+type Stats struct {
+	mu       sync.Mutex
+	requests []RequestStat
+}
+
+// NewStats returns an empty Stats
+func NewStats() *Stats { return &Stats{} }
+
+// Record adds a request to the stats
+func (s *Stats) Record(r RequestStat) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+}
+
+// Requests returns every request recorded, in the order they completed
+func (s *Stats) Requests() []RequestStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RequestStat{}, s.requests...)
+}
+
+// EndpointStat is the total for every request to a single endpoint
+type EndpointStat struct {
+	Method   string        `json:"method"`
+	Endpoint string        `json:"endpoint"`
+	Count    int           `json:"count"`
+	Cached   int           `json:"cached"`
+	Total    time.Duration `json:"total_ns"`
+	Slowest  time.Duration `json:"slowest_ns"`
+	Bytes    int64         `json:"bytes"`
+}
+
+// StatsSummary is the summary of every request recorded
+type StatsSummary struct {
+	Count     int            `json:"count"`
+	Cached    int            `json:"cached"`
+	Errors    int            `json:"errors"`
+	Total     time.Duration  `json:"total_ns"`
+	Bytes     int64          `json:"bytes"`
+	Endpoints []EndpointStat `json:"endpoints"`
+	Slowest   []RequestStat  `json:"slowest"`
+	Requests  []RequestStat  `json:"requests"`
+}
+
+// Summary totals the requests, overall and per endpoint, and picks out the
+// slowest n
+func (s *Stats) Summary(n int) StatsSummary {
+	requests := s.Requests()
+	summary := StatsSummary{Requests: requests}
+
+	endpoints := map[string]*EndpointStat{}
+	order := []string{}
+	for _, r := range requests {
+		summary.Count++
+		summary.Total += r.Duration
+		summary.Bytes += r.Bytes
+		if r.Cached {
+			summary.Cached++
+		}
+		if r.Error != "" || r.Status >= 400 {
+			summary.Errors++
+		}
+
+		key := r.Method + " " + r.Endpoint
+		e, ok := endpoints[key]
+		if !ok {
+			e = &EndpointStat{Method: r.Method, Endpoint: r.Endpoint}
+			endpoints[key] = e
+			order = append(order, key)
+		}
+		e.Count++
+		e.Total += r.Duration
+		e.Bytes += r.Bytes
+		if r.Cached {
+			e.Cached++
+		}
+		if r.Duration > e.Slowest {
+			e.Slowest = r.Duration
+		}
+	}
+
+	summary.Endpoints = []EndpointStat{}
+	for _, key := range order {
+		summary.Endpoints = append(summary.Endpoints, *endpoints[key])
+	}
+	sort.SliceStable(summary.Endpoints, func(i, j int) bool {
+		return summary.Endpoints[i].Total > summary.Endpoints[j].Total
+	})
+
+	slowest := append([]RequestStat{}, requests...)
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].Duration > slowest[j].Duration })
+	if len(slowest) > n {
+		slowest = slowest[:n]
+	}
+	summary.Slowest = slowest
+
+	return summary
+}
+
+var (
+	uuidSegment  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	digitSegment = regexp.MustCompile(`[0-9]`)
+)
+
+// endpoint reduces a request path to its endpoint by replacing the segments
+// that look like identifiers (UUIDs, or anything containing a digit, such as
+// serial numbers) with ":id", so that requests for different objects are
+// totalled together
+func endpoint(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, s := range segments {
+		if uuidSegment.MatchString(s) || digitSegment.MatchString(s) {
+			segments[i] = ":id"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// Inflight lets clients that share it answer a GET from an identical one,
+// with the same URL and token, that is already in flight instead of making
+// another request. Only requests made at the same time are shared, so nothing
+// is answered with a response that may have gone stale. It is safe for
+// concurrent use.
+type Inflight struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a GET in progress and, once done is closed, its result
+type flight struct {
+	done chan struct{}
+	res  *http.Response
+	body []byte
+	err  error
+}
+
+// NewInflight returns an empty Inflight
+func NewInflight() *Inflight { return &Inflight{flights: map[string]*flight{}} }
+
+func inflightKey(req *http.Request) string {
+	return req.URL.String() + "\x00" + req.Header.Get("Authorization")
+}
+
+// join returns the flight for a request and whether the caller leads it, and
+// so must make the request and land it. Requests that can't be shared are
+// always led, by themselves.
+func (i *Inflight) join(req *http.Request) (*flight, bool) {
+	if i == nil || req.Method != http.MethodGet {
+		return nil, true
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	key := inflightKey(req)
+	if f, ok := i.flights[key]; ok {
+		return f, false
+	}
+	f := &flight{done: make(chan struct{})}
+	i.flights[key] = f
+	return f, true
+}
+
+// land records the result of a flight and releases everyone waiting on it
+func (i *Inflight) land(req *http.Request, f *flight, res *http.Response, body []byte, err error) {
+	if f == nil {
+		return
+	}
+	i.mu.Lock()
+	delete(i.flights, inflightKey(req))
+	i.mu.Unlock()
+	f.res, f.body, f.err = res, body, err
+	close(f.done)
+}
+
+// capturingDecoder is a sling.ResponseDecoder that keeps a copy of the body
+// it decodes
+type capturingDecoder struct {
+	body *[]byte
+}
+
+func (d capturingDecoder) Decode(res *http.Response, v interface{}) error {
+	b, e := ioutil.ReadAll(res.Body)
+	if e != nil {
+		return e
+	}
+	*d.body = b
+	return decodeBody(b, v)
+}
+
+// decodeBody decodes a JSON response body into v. If v is a *[]byte the body
+// is copied into it as it is, whatever its content type.
+func decodeBody(b []byte, v interface{}) error {
+	if raw, ok := v.(*[]byte); ok {
+		*raw = append([]byte{}, b...)
+		return nil
+	}
+	return json.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
@@ -0,0 +1,92 @@
+package conch_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"name":"me"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	stats := conch.NewStats()
+	c := conch.New(
+		conch.API(ts.URL),
+		conch.RecordStats(stats),
+	)
+
+	for i := 0; i < 2; i++ {
+		me, e := c.GetCurrentUser()
+		assert.Nil(t, e)
+		assert.Equal(t, "me", string(me.Name))
+	}
+	assert.Nil(t, c.SetCurrentUserSettings(types.UserSettings{}))
+	assert.Equal(t, 3, hits, "every request reaches the server")
+
+	summary := stats.Summary(2)
+	assert.Equal(t, 3, summary.Count)
+	assert.Len(t, summary.Slowest, 2)
+	assert.Equal(t, int64(13), summary.Requests[0].Bytes)
+
+	endpoints := map[string]int{}
+	for _, e := range summary.Endpoints {
+		endpoints[e.Method+" "+e.Endpoint] = e.Count
+	}
+	assert.Equal(t, 2, endpoints["GET /user/me"])
+}
+
+func TestInflightGETsAreShared(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		// long enough for every other GET to join this one
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{"name":"me"}`))
+	}))
+	defer ts.Close()
+
+	stats := conch.NewStats()
+	c := conch.New(
+		conch.API(ts.URL),
+		conch.RecordStats(stats),
+		conch.ShareInflight(conch.NewInflight()),
+	)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			me, e := c.GetCurrentUser()
+			assert.Nil(t, e)
+			assert.Equal(t, "me", string(me.Name))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+
+	summary := stats.Summary(3)
+	assert.Equal(t, 3, summary.Count)
+	assert.Equal(t, 2, summary.Cached)
+
+	// once it has landed, the next GET makes its own request
+	_, e := c.GetCurrentUser()
+	assert.Nil(t, e)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+}
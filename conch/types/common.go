@@ -44,9 +44,6 @@ func NewDeviceLinks(uris ...string) DeviceLinks {
 	return DeviceLinks{links}
 }
 
-// IntOrStringyInt is an integer that may be presented as a json string
-type IntOrStringyInt interface{}
-
 // DiskSizeItem is an int
 type DiskSizeItem int
 
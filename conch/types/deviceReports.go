@@ -0,0 +1,188 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// IntOrStringyInt is an integer that reporters may send as a JSON number or
+// as a string holding one, as they often do for slots and temperatures.
+// Quoted records which it was so that it is written back the same way.
+type IntOrStringyInt struct {
+	Int    int
+	Quoted bool
+}
+
+// NewIntOrStringyInt returns an IntOrStringyInt written as a JSON number
+func NewIntOrStringyInt(n int) *IntOrStringyInt {
+	return &IntOrStringyInt{Int: n}
+}
+
+// Value returns the integer, or 0 if there isn't one
+func (i *IntOrStringyInt) Value() int {
+	if i == nil {
+		return 0
+	}
+	return i.Int
+}
+
+func (i *IntOrStringyInt) String() string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(i.Int)
+}
+
+// UnmarshalJSON accepts an integer or a string holding one
+func (i *IntOrStringyInt) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	quoted := strings.HasPrefix(s, `"`)
+	if quoted {
+		if e := json.Unmarshal(b, &s); e != nil {
+			return e
+		}
+	}
+	n, e := strconv.Atoi(s)
+	if e != nil {
+		return fmt.Errorf("%s is not an integer or a string holding one", b)
+	}
+	*i = IntOrStringyInt{Int: n, Quoted: quoted}
+	return nil
+}
+
+// MarshalJSON writes the integer as it was read
+func (i IntOrStringyInt) MarshalJSON() ([]byte, error) {
+	s := strconv.Itoa(i.Int)
+	if i.Quoted {
+		return json.Marshal(s)
+	}
+	return []byte(s), nil
+}
+
+// CpusItem is a single CPU in a device report. The schema allows any fields,
+// so those that aren't set here, or don't have the expected type, are kept
+// as they were in Extra.
+type CpusItem struct {
+	Socket  *IntOrStringyInt `json:"socket,omitempty"`
+	Vendor  string           `json:"vendor,omitempty"`
+	Model   string           `json:"model,omitempty"`
+	Cores   *IntOrStringyInt `json:"cores,omitempty"`
+	Threads *IntOrStringyInt `json:"threads,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// cpusItemFields are the fields of a CpusItem, as pointers into it, by their
+// names in JSON
+func (c *CpusItem) cpusItemFields() map[string]interface{} {
+	return map[string]interface{}{
+		"socket":  &c.Socket,
+		"vendor":  &c.Vendor,
+		"model":   &c.Model,
+		"cores":   &c.Cores,
+		"threads": &c.Threads,
+	}
+}
+
+// UnmarshalJSON sets the fields of the CpusItem, keeping any other fields,
+// and any that are empty or of an unexpected type, in Extra
+func (c *CpusItem) UnmarshalJSON(b []byte) error {
+	raw := map[string]json.RawMessage{}
+	if e := json.Unmarshal(b, &raw); e != nil {
+		return e
+	}
+	*c = CpusItem{}
+	fields := c.cpusItemFields()
+	for name, value := range raw {
+		if field, ok := fields[name]; ok {
+			v := reflect.ValueOf(field).Elem()
+			if json.Unmarshal(value, field) == nil && !v.IsZero() {
+				continue
+			}
+			v.Set(reflect.Zero(v.Type()))
+		}
+		if c.Extra == nil {
+			c.Extra = map[string]json.RawMessage{}
+		}
+		c.Extra[name] = value
+	}
+	return nil
+}
+
+// MarshalJSON writes the fields of the CpusItem and those in Extra as a
+// single object
+func (c CpusItem) MarshalJSON() ([]byte, error) {
+	// cpusItem has the fields but not the methods of CpusItem
+	type cpusItem CpusItem
+	b, e := json.Marshal(cpusItem(c))
+	if e != nil {
+		return nil, e
+	}
+	raw := map[string]json.RawMessage{}
+	if e := json.Unmarshal(b, &raw); e != nil {
+		return nil, e
+	}
+	for name, value := range c.Extra {
+		if _, ok := raw[name]; !ok {
+			raw[name] = value
+		}
+	}
+	return json.Marshal(raw)
+}
+
+// DiskSizeUnit is the number of bytes in the unit, MB, that device reports
+// record disk sizes in
+const DiskSizeUnit = 1024 * 1024
+
+// NoEnclosure is the key DisksByEnclosure uses for disks that don't report
+// an enclosure
+const NoEnclosure = -1
+
+// TotalMemoryGiB returns the total size of the report's DIMMs. Their sizes
+// are reported in GiB.
+func (d DeviceReport) TotalMemoryGiB() int {
+	total := 0
+	for _, dimm := range d.Dimms {
+		total += dimm.MemorySize.Value()
+	}
+	return total
+}
+
+// DisksByEnclosure groups the report's disks, keyed by serial number, by the
+// enclosure they are in, with those that don't report one under NoEnclosure
+func (d DeviceReport) DisksByEnclosure() map[int]map[string]Disk {
+	enclosures := map[int]map[string]Disk{}
+	for serial, disk := range d.Disks {
+		enclosure := NoEnclosure
+		if disk.Enclosure != nil {
+			enclosure = disk.Enclosure.Int
+		}
+		if enclosures[enclosure] == nil {
+			enclosures[enclosure] = map[string]Disk{}
+		}
+		enclosures[enclosure][serial] = disk
+	}
+	return enclosures
+}
+
+// normalizeMAC lower cases a MAC address and separates it with colons, so
+// that addresses written differently compare equal
+func normalizeMAC(mac string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(mac)), "-", ":")
+}
+
+// InterfaceByMAC returns the name and details of the report's interface with
+// the given MAC address, ignoring case and whether it is separated with
+// colons or dashes
+func (d DeviceReport) InterfaceByMAC(mac string) (string, Interface, bool) {
+	want := normalizeMAC(mac)
+	for name, iface := range d.Interfaces {
+		if normalizeMAC(string(iface.Mac)) == want {
+			return name, iface, true
+		}
+	}
+	return "", Interface{}, false
+}
@@ -0,0 +1,77 @@
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/joyent/kosh/conch/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// testReport is written the way the API and reporters write reports, mixing
+// numbers and numeric strings
+const testReport = `{"bios_version":"2.8.2",` +
+	`"cpus":[{"cores":"18","model":"Xeon Gold 6140","socket":0,"speed_mhz":2300.5,"threads":36,"vendor":"GenuineIntel"},{"model":"","socket":"1"}],` +
+	`"device_type":"server",` +
+	`"dimms":[{"memory-locator":"P1-DIMMA1","memory-serial-number":"15C7A1B2","memory-size":32},{"memory-locator":"P1-DIMMB1","memory-size":"16"},{"memory-locator":"P1-DIMMC1"}],` +
+	`"disks":{"D1":{"drive_type":"SAS_HDD","enclosure":"2","model":"ST4000","size":4000,"slot":"0","temp":31},"D2":{"enclosure":2,"slot":1},"D3":{"slot":3}},` +
+	`"interfaces":{"eth0":{"ipaddr":"10.0.0.5","mac":"00:1B:21:AA:BB:CC","mtu":"9000","peer_mac":"a8:2b:b5:00:00:01","product":"X710","state":"up","vendor":"Intel"},"eth1":{"mac":"00:1b:21:aa:bb:cd","mtu":1500,"product":"X710","vendor":"Intel"}},` +
+	`"product_name":"Joyent-Compute-Platform-3301",` +
+	`"serial_number":"S1",` +
+	`"sku":"600-0032-001",` +
+	`"system_uuid":"4c4c4544-0042-3510-8051-b3c04f4e4d32",` +
+	`"temp":{"cpu0":"45","cpu1":47,"inlet":22}}`
+
+func TestDeviceReportRoundTrip(t *testing.T) {
+	report := types.DeviceReport{}
+	assert.Nil(t, json.Unmarshal([]byte(testReport), &report))
+
+	b, e := json.Marshal(report)
+	assert.Nil(t, e)
+	assert.Equal(t, testReport, string(b))
+}
+
+func TestDeviceReportTypedFields(t *testing.T) {
+	report := types.DeviceReport{}
+	assert.Nil(t, json.Unmarshal([]byte(testReport), &report))
+
+	assert.Equal(t, 18, report.Cpus[0].Cores.Value())
+	assert.Equal(t, 1, report.Cpus[1].Socket.Value())
+	assert.Equal(t, "", report.Cpus[1].Model)
+	assert.Equal(t, json.RawMessage(`2300.5`), report.Cpus[0].Extra["speed_mhz"])
+
+	assert.Equal(t, 2, report.Disks["D1"].Enclosure.Value())
+	assert.Equal(t, 0, report.Disks["D1"].Slot.Value())
+	assert.Nil(t, report.Disks["D3"].Enclosure)
+	assert.Equal(t, 9000, report.Interfaces["eth0"].Mtu.Value())
+	assert.Equal(t, types.Ipaddr("10.0.0.5"), report.Interfaces["eth0"].Ipaddr)
+	assert.Equal(t, 45, report.Temp.CPU0.Value())
+	assert.Equal(t, "", report.Temp.Exhaust.String())
+}
+
+func TestIntOrStringyIntRejectsNonIntegers(t *testing.T) {
+	for _, b := range []string{`"two"`, `1.5`, `true`, `""`} {
+		i := types.IntOrStringyInt{}
+		assert.NotNil(t, json.Unmarshal([]byte(b), &i), b)
+	}
+}
+
+func TestDeviceReportHelpers(t *testing.T) {
+	report := types.DeviceReport{}
+	assert.Nil(t, json.Unmarshal([]byte(testReport), &report))
+
+	assert.Equal(t, 48, report.TotalMemoryGiB())
+
+	enclosures := report.DisksByEnclosure()
+	assert.Len(t, enclosures, 2)
+	assert.Len(t, enclosures[2], 2)
+	assert.Contains(t, enclosures[types.NoEnclosure], "D3")
+
+	name, iface, ok := report.InterfaceByMAC("00-1b-21-aa-bb-cc")
+	assert.True(t, ok)
+	assert.Equal(t, "eth0", name)
+	assert.Equal(t, "X710", iface.Product)
+
+	_, _, ok = report.InterfaceByMAC("00:00:00:00:00:00")
+	assert.False(t, ok)
+}
@@ -79,10 +79,6 @@ type DeviceLinks struct {
 	Links []Link `json:"links"`
 }
 
-// CpusItem is a map of strings to data
-// generated by "schematyper -o RequestType_DeviceReport.go --package=types --ptr-for-omit DeviceReport.json" -- DO NOT EDIT
-type CpusItem map[string]interface{}
-
 // DeviceReport is a struct of the contents of a posted device report from
 // relays and reporters
 type DeviceReport struct {
@@ -105,25 +101,25 @@ type DeviceReport struct {
 
 // Dimm is a struct of memory information
 type Dimm struct {
-	MemoryLocator      string      `json:"memory-locator"`
-	MemorySerialNumber interface{} `json:"memory-serial-number,omitempty"`
-	MemorySize         interface{} `json:"memory-size,omitempty"`
+	MemoryLocator      string           `json:"memory-locator"`
+	MemorySerialNumber interface{}      `json:"memory-serial-number,omitempty"`
+	MemorySize         *IntOrStringyInt `json:"memory-size,omitempty"`
 }
 
 // Disk is a struct of disk information
 type Disk struct {
-	BlockSz   int             `json:"block_sz,omitempty"`
-	DriveType string          `json:"drive_type,omitempty"`
-	Enclosure IntOrStringyInt `json:"enclosure,omitempty"`
-	Firmware  string          `json:"firmware,omitempty"`
-	Hba       IntOrStringyInt `json:"hba,omitempty"`
-	Health    string          `json:"health,omitempty"`
-	Model     string          `json:"model,omitempty"`
-	Size      int             `json:"size,omitempty"`
-	Slot      IntOrStringyInt `json:"slot,omitempty"`
-	Temp      IntOrStringyInt `json:"temp,omitempty"`
-	Transport string          `json:"transport,omitempty"`
-	Vendor    string          `json:"vendor,omitempty"`
+	BlockSz   int              `json:"block_sz,omitempty"`
+	DriveType string           `json:"drive_type,omitempty"`
+	Enclosure *IntOrStringyInt `json:"enclosure,omitempty"`
+	Firmware  string           `json:"firmware,omitempty"`
+	Hba       *IntOrStringyInt `json:"hba,omitempty"`
+	Health    string           `json:"health,omitempty"`
+	Model     string           `json:"model,omitempty"`
+	Size      int              `json:"size,omitempty"`
+	Slot      *IntOrStringyInt `json:"slot,omitempty"`
+	Temp      *IntOrStringyInt `json:"temp,omitempty"`
+	Transport string           `json:"transport,omitempty"`
+	Vendor    string           `json:"vendor,omitempty"`
 }
 
 // DiskSerialNumber is a string
@@ -131,13 +127,13 @@ type DiskSerialNumber string
 
 // Interface is a struct of network interface information
 type Interface struct {
-	Ipaddr  interface{} `json:"ipaddr,omitempty"`
-	Mac     Macaddr     `json:"mac"`
-	Mtu     interface{} `json:"mtu,omitempty"`
-	PeerMac interface{} `json:"peer_mac,omitempty"`
-	Product string      `json:"product"`
-	State   interface{} `json:"state,omitempty"`
-	Vendor  string      `json:"vendor"`
+	Ipaddr  Ipaddr           `json:"ipaddr,omitempty"`
+	Mac     Macaddr          `json:"mac"`
+	Mtu     *IntOrStringyInt `json:"mtu,omitempty"`
+	PeerMac Macaddr          `json:"peer_mac,omitempty"`
+	Product string           `json:"product"`
+	State   string           `json:"state,omitempty"`
+	Vendor  string           `json:"vendor"`
 }
 
 // Os is a struct of OS inforamtion
@@ -147,10 +143,10 @@ type Os struct {
 
 // Temp is a struct of temperature data
 type Temp struct {
-	CPU0    IntOrStringyInt `json:"cpu0"`
-	CPU1    IntOrStringyInt `json:"cpu1"`
-	Exhaust IntOrStringyInt `json:"exhaust,omitempty"`
-	Inlet   IntOrStringyInt `json:"inlet,omitempty"`
+	CPU0    *IntOrStringyInt `json:"cpu0"`
+	CPU1    *IntOrStringyInt `json:"cpu1"`
+	Exhaust *IntOrStringyInt `json:"exhaust,omitempty"`
+	Inlet   *IntOrStringyInt `json:"inlet,omitempty"`
 }
 
 // HardwareProductCreate is a struct
@@ -39,7 +39,7 @@ type BuildOrganization struct {
 	ID          UUID       `json:"id"`
 	Name        string     `json:"name"`
 	Description string     `json:"description"`
-	Admins      UsersTerse `json: "admins"`
+	Admins      UsersTerse `json:"admins"`
 	Role        Role       `json:"role"`
 }
 
@@ -161,18 +161,18 @@ type DeviceReportV300 struct {
 
 // DeviceReportV300Disk is a struct of Disk data
 type DeviceReportV300Disk struct {
-	BlockSz   int             `json:"block_sz,omitempty"`
-	DriveType string          `json:"drive_type,omitempty"`
-	Enclosure IntOrStringyInt `json:"enclosure,omitempty"`
-	Firmware  string          `json:"firmware,omitempty"`
-	Hba       IntOrStringyInt `json:"hba,omitempty"`
-	Health    string          `json:"health,omitempty"`
-	Model     string          `json:"model,omitempty"`
-	Size      int             `json:"size,omitempty"`
-	Slot      IntOrStringyInt `json:"slot,omitempty"`
-	Temp      IntOrStringyInt `json:"temp,omitempty"`
-	Transport string          `json:"transport,omitempty"`
-	Vendor    string          `json:"vendor,omitempty"`
+	BlockSz   int              `json:"block_sz,omitempty"`
+	DriveType string           `json:"drive_type,omitempty"`
+	Enclosure *IntOrStringyInt `json:"enclosure,omitempty"`
+	Firmware  string           `json:"firmware,omitempty"`
+	Hba       *IntOrStringyInt `json:"hba,omitempty"`
+	Health    string           `json:"health,omitempty"`
+	Model     string           `json:"model,omitempty"`
+	Size      int              `json:"size,omitempty"`
+	Slot      *IntOrStringyInt `json:"slot,omitempty"`
+	Temp      *IntOrStringyInt `json:"temp,omitempty"`
+	Transport string           `json:"transport,omitempty"`
+	Vendor    string           `json:"vendor,omitempty"`
 }
 
 // DeviceReportV300Link is a strign
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/joyent/kosh/logger"
 	"github.com/joyent/kosh/tables"
 	"github.com/joyent/kosh/template"
 )
@@ -191,8 +192,8 @@ Hostname: {{ .Hostname }}
 System UUID: {{ .SystemUUID }}
 
 Phase: {{ .Phase }}
-Health: {{ .Health }}
-Validated: {{ if not $.Validated.IsZero }}{{ .Validated.Local }}{{ end }}
+Health: {{ Status .Health }}
+Validated: {{ TimeStr .Validated }}
 
 Created:   {{ TimeStr .Created }}
 Updated:   {{ TimeStr .Updated }}
@@ -224,8 +225,8 @@ Disks:{{range $name, $slots := .Disks}}
         Type:   {{ .DriveType }}
         Vendor: {{ .Vendor }}
         Model:  {{ .Model }}
-        Size:   {{ .Size }}
-        Health: {{ .Health }}
+        Size:   {{ MegaBytes .Size }}
+        Health: {{ Status .Health }}
         Firmware: {{ .Firmware }}
         Transport: {{ .Transport }}
 {{ end }}{{ end }}
@@ -245,8 +246,8 @@ Hostname: {{ .Hostname }}
 System UUID: {{ .SystemUUID }}
 
 Phase: {{ .Phase }}
-Health: {{ .Health }}
-Validated: {{ if not $.Validated.IsZero }}{{ .Validated.Local }}{{ end }}
+Health: {{ Status .Health }}
+Validated: {{ TimeStr .Validated }}
 
 Created:   {{ TimeStr .Created }}
 Updated:   {{ TimeStr .Updated }}
@@ -343,8 +344,8 @@ func (h HardwareProducts) String() string {
 			string(hp.Name),
 			string(hp.Alias),
 			hp.GenerationName,
-			hp.Created.String(),
-			hp.Updated.String(),
+			template.TimeStr(hp.Created),
+			template.TimeStr(hp.Updated),
 		})
 	}
 	table.Render()
@@ -514,6 +515,9 @@ func (ra RackAssignments) Len() int           { return len(ra) }
 func (ra RackAssignments) Swap(i, j int)      { ra[i], ra[j] = ra[j], ra[i] }
 func (ra RackAssignments) Less(i, j int) bool { return ra[i].RackUnitStart > ra[j].RackUnitStart }
 
+// IdentityColumn returns the header of the column that identifies a row
+func (ra RackAssignments) IdentityColumn() string { return "Rack Unit Start" }
+
 // Headers returns the list of headers for the table view
 func (ra RackAssignments) Headers() []string {
 	return []string{
@@ -732,7 +736,7 @@ Validation Plan {{ .Name }}
 ID: {{ .ID }}
 Name: {{ .Name }}
 Description: {{ .Description }}
-Created: {{ .Created }}
+Created: {{ TimeStr .Created }}
 `
 
 // Template returns a template string for rendering to Markdown
@@ -761,7 +765,7 @@ func (v ValidationPlans) String() string {
 			template.CutUUID(p.ID.String()),
 			string(p.Name),
 			p.Description,
-			p.Created.String(),
+			template.TimeStr(p.Created),
 		})
 	}
 
@@ -777,7 +781,7 @@ ID: {{ .ID }}
 Device: {{ CutUUID .DeviceID.String }}
 Hardware Product: {{ CutUUID .HardwareProductID.String }}
 Created: {{ TimeStr .Created }}
-Status: {{ .Status }}
+Status: {{ Status .Status }}
 
 Results:
 {{ .Results }}
@@ -806,7 +810,7 @@ func (v ValidationResults) String() string {
 
 	for _, r := range v {
 		table.Append([]string{
-			string(r.Status),
+			template.Status(r.Status),
 			r.Category,
 			r.Component,
 			r.Message,
@@ -947,6 +951,22 @@ THIS IS THE ONLY TIME IT WILL BE PRINTED, PLEASE RECORD IT NOW
 // Template returns a template string for rendering to Markdown
 func (ut NewUserTokenResponse) Template() string { return newUserTokenTemplate }
 
+// Redacted returns a copy of the response that is safe to log
+func (ut NewUserTokenResponse) Redacted() interface{} {
+	if ut.Token != "" {
+		ut.Token = logger.Redacted
+	}
+	return ut
+}
+
+// Redacted returns a copy of the login token that is safe to log
+func (lt LoginToken) Redacted() interface{} {
+	if lt.JwtToken != "" {
+		lt.JwtToken = logger.Redacted
+	}
+	return lt
+}
+
 func (ul UserTokens) Len() int           { return len(ul) }
 func (ul UserTokens) Swap(i, j int)      { ul[i], ul[j] = ul[j], ul[i] }
 func (ul UserTokens) Less(i, j int) bool { return ul[i].Name < ul[j].Name }
@@ -974,3 +994,29 @@ func (ul UserTokens) ForEach(do func([]string)) {
 		})
 	}
 }
+
+// Templates returns a zero value of every type that has a built in template,
+// so that the templates can be listed and overridden by name
+func Templates() []template.Templated {
+	return []template.Templated{
+		Build{},
+		Datacenter{},
+		DatacenterRoomDetailed{},
+		DetailedDevice{},
+		Device{},
+		DeviceLocation{},
+		DeviceNic{},
+		DeviceReport{},
+		HardwareProduct{},
+		HardwareVendor{},
+		NewUserTokenResponse{},
+		Organization{},
+		Rack{},
+		RackRole{},
+		Relay{},
+		UserDetailed{},
+		UserToken{},
+		ValidationPlan{},
+		ValidationStateWithResults{},
+	}
+}
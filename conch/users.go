@@ -105,8 +105,8 @@ func (c *Client) GetUserByID(id types.UUID) (user types.UserDetailed, e error) {
 }
 
 // ReadUser takes an io.Reader and returns a UserDetailed object
-func (c *Client) ReadUser(r io.Reader) (user types.UserDetailed) {
-	json.NewDecoder(r).Decode(&user)
+func (c *Client) ReadUser(r io.Reader) (user types.UserDetailed, e error) {
+	e = json.NewDecoder(r).Decode(&user)
 	return
 }
 
@@ -7,9 +7,9 @@ require (
 	github.com/dnaeon/go-vcr v1.0.1
 	github.com/gofrs/uuid v3.2.0+incompatible
 	github.com/jawher/mow.cli v1.1.0
-	github.com/mattn/go-runewidth v0.0.4 // indirect
+	github.com/mattn/go-runewidth v0.0.4
 	github.com/olekukonko/tablewriter v0.0.1
 	github.com/qri-io/jsonschema v0.2.0
 	github.com/stretchr/testify v1.4.0
-	gopkg.in/yaml.v2 v2.2.4 // indirect
+	gopkg.in/yaml.v2 v2.2.4
 )
@@ -6,22 +6,39 @@ Package logger is a simple logging module based upon the advice of Dave Cheney
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"net/http/httputil"
+	"os"
+	"sort"
+	"strings"
+	"time"
 )
 
 // Interface is the default interface for logging with this logger. Debug() is
 // for developer targeted output. While Info is for (verbose) user targeted
-// output.
+// output. Warn and Error are always shown.
+//
+// Any message may be a Fields value, whose keys and values are attached to
+// the log entry as structured data rather than being formatted into the
+// message.
 type Interface interface {
 	// Debug outputs developer targeted messaging.
 	Debug(...interface{})
 	// Info outputs more verbose user targed information
 	Info(...interface{})
+	// Warn outputs something the user should know about but that didn't stop
+	// the command
+	Warn(...interface{})
+	// Error outputs a failure
+	Error(...interface{})
 }
 
+// Fields holds the structured data for a log entry
+type Fields map[string]interface{}
+
 // NullLogger is a default logger that doesn't output anything.
 type NullLogger struct{}
 
@@ -31,49 +48,190 @@ func (nl NullLogger) Debug(msgs ...interface{}) {}
 // Info outputs more verbose user targed information
 func (nl NullLogger) Info(msgs ...interface{}) {}
 
+// Warn outputs something the user should know about
+func (nl NullLogger) Warn(msgs ...interface{}) {}
+
+// Error outputs a failure
+func (nl NullLogger) Error(msgs ...interface{}) {}
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Level is the severity of a log entry
+type Level string
+
+// Log levels, in increasing order of severity
+const (
+	DebugLevel Level = "debug"
+	InfoLevel  Level = "info"
+	WarnLevel  Level = "warn"
+	ErrorLevel Level = "error"
+)
+
 // Logger is the default logger with configuration levels for debug (developer)
-// output, and info (verbose user) output.
+// output, and info (verbose user) output. Secrets are redacted from every
+// message unless Unsafe is set.
+//
+// Everything is written to STDERR, so that it never mixes with command
+// output. If File is set, a complete trace of every entry at info level and
+// above (and debug, if enabled) is also written there, regardless of
+// LevelInfo.
 type Logger struct {
 	LevelDebug bool
 	LevelInfo  bool
+	Unsafe     bool
+
+	// Format is either FormatText (the default) or FormatJSON
+	Format string
+	// File receives a copy of every entry
+	File io.Writer
 }
 
 // New returns a new instance of the Logger struct
 func New() Logger { return Logger{} }
 
+// stderr is replaced in tests
+var stderr io.Writer = os.Stderr
+
+// now is replaced in tests
+var now = time.Now
+
 // Debug outputs developer targeted messaging.
 func (l Logger) Debug(messages ...interface{}) {
 	if l.LevelDebug {
-		for _, m := range messages {
-			if m == nil {
-				continue
-			}
-			switch t := m.(type) {
-			case *http.Request:
-				dump, e := httputil.DumpRequestOut(t, true)
-				if e != nil {
-					log.Println("Got error:", e)
-				}
-				log.Println("Request:", string(dump))
-			case *http.Response:
-				dump, e := httputil.DumpResponse(t, false)
-				if e != nil {
-					l.Debug(fmt.Sprintf("Dump Response Error: %s", e))
-				}
-				l.Debug("Response:", string(dump))
+		l.log(DebugLevel, true, messages)
+	}
+}
+
+// Info outputs more verbose user targed information
+func (l Logger) Info(messages ...interface{}) {
+	l.log(InfoLevel, l.LevelInfo, messages)
+}
+
+// Warn outputs something the user should know about but that didn't stop
+// the command
+func (l Logger) Warn(messages ...interface{}) {
+	l.log(WarnLevel, true, messages)
+}
+
+// Error outputs a failure
+func (l Logger) Error(messages ...interface{}) {
+	l.log(ErrorLevel, true, messages)
+}
+
+// entry is a single log record
+type entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  Fields
+}
 
-			default:
-				log.Println(t)
+func (l Logger) log(level Level, console bool, messages []interface{}) {
+	if !console && l.File == nil {
+		return
+	}
+
+	e := entry{Time: now(), Level: level, Fields: Fields{}}
+	text := []string{}
+	for _, m := range messages {
+		if m == nil {
+			continue
+		}
+		if f, ok := m.(Fields); ok {
+			for k, v := range f {
+				if s, isString := v.(string); isString && !l.Unsafe {
+					if sensitiveKey.MatchString(k) {
+						v = Redacted
+					} else {
+						v = RedactString(s)
+					}
+				}
+				e.Fields[k] = v
 			}
+			continue
 		}
+		text = append(text, l.format(m))
+	}
+	e.Message = strings.Join(text, " ")
+
+	if console {
+		fmt.Fprint(stderr, l.encode(e))
+	}
+	if l.File != nil {
+		fmt.Fprint(l.File, l.encode(e))
 	}
 }
 
-// Info outputs more verbose user targed information
-func (l Logger) Info(messages ...interface{}) {
-	if l.LevelInfo {
-		for _, m := range messages {
-			fmt.Println(m)
+// format turns a single message into text, dumping HTTP requests and
+// responses
+func (l Logger) format(m interface{}) string {
+	switch t := m.(type) {
+	case *http.Request:
+		if !l.Unsafe {
+			return "Request: " + dumpRequest(t)
+		}
+		dump, e := httputil.DumpRequestOut(t, true)
+		if e != nil {
+			return fmt.Sprintf("Dump Request Error: %s", e)
+		}
+		return "Request: " + string(dump)
+	case *http.Response:
+		if !l.Unsafe {
+			return "Response: " + dumpResponse(t)
+		}
+		dump, e := httputil.DumpResponse(t, false)
+		if e != nil {
+			return fmt.Sprintf("Dump Response Error: %s", e)
+		}
+		return "Response: " + string(dump)
+	}
+
+	if !l.Unsafe {
+		m = redact(m)
+	}
+	return fmt.Sprint(m)
+}
+
+func (l Logger) encode(e entry) string {
+	if l.Format == FormatJSON {
+		record := make(map[string]interface{}, len(e.Fields)+3)
+		for k, v := range e.Fields {
+			record[k] = v
+		}
+		record["time"] = e.Time.Format(time.RFC3339Nano)
+		record["level"] = e.Level
+		record["msg"] = e.Message
+
+		b, err := json.Marshal(record)
+		if err != nil {
+			b, _ = json.Marshal(map[string]interface{}{
+				"time":  record["time"],
+				"level": ErrorLevel,
+				"msg":   fmt.Sprintf("unable to encode log entry: %s", err),
+			})
+		}
+		return string(b) + "\n"
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s %-5s %s", e.Time.Format(time.RFC3339), strings.ToUpper(string(e.Level)), e.Message)
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := fmt.Sprint(e.Fields[k])
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = fmt.Sprintf("%q", v)
 		}
+		fmt.Fprintf(b, " %s=%s", k, v)
 	}
+	b.WriteString("\n")
+	return b.String()
 }
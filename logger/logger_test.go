@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func capture(t *testing.T) (*bytes.Buffer, func()) {
+	buf := &bytes.Buffer{}
+	oldStderr, oldNow := stderr, now
+	stderr = buf
+	now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return buf, func() { stderr, now = oldStderr, oldNow }
+}
+
+func TestLevels(t *testing.T) {
+	buf, restore := capture(t)
+	defer restore()
+
+	l := New()
+	l.Debug("hidden")
+	l.Info("hidden")
+	l.Warn("careful")
+	l.Error("broken", Fields{"code": 3})
+	assert.Equal(
+		t,
+		"2020-01-02T03:04:05Z WARN  careful\n"+
+			"2020-01-02T03:04:05Z ERROR broken code=3\n",
+		buf.String(),
+	)
+}
+
+func TestFileAndJSON(t *testing.T) {
+	buf, restore := capture(t)
+	defer restore()
+
+	file := &bytes.Buffer{}
+	l := Logger{Format: FormatJSON, File: file}
+	l.Info("request", Fields{"method": "GET", "status": 200, "token": "abc"})
+
+	// info isn't enabled on the console but always goes to the file
+	assert.Equal(t, "", buf.String())
+
+	record := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(file.Bytes(), &record))
+	assert.Equal(t, map[string]interface{}{
+		"time":   "2020-01-02T03:04:05Z",
+		"level":  "info",
+		"msg":    "request",
+		"method": "GET",
+		"status": float64(200),
+		"token":  Redacted,
+	}, record)
+}
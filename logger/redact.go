@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secrets in log output
+const Redacted = "REDACTED"
+
+// Redactor is implemented by values that carry secrets. Redacted returns a
+// copy that is safe to log.
+type Redactor interface {
+	Redacted() interface{}
+}
+
+// sensitiveHeaders are the HTTP headers whose values are never logged
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Auth-Token",
+}
+
+// sensitiveKey matches the names of JSON fields and struct fields that hold
+// secrets: token, jwt_token, JwtToken, password, new_password and the like
+var sensitiveKey = regexp.MustCompile(`(?i)(token|password|passwd|secret)$`)
+
+var (
+	bearerRe = regexp.MustCompile(`(?i)\b(Bearer|Basic)\s+[^\s"',}]+`)
+	jsonRe   = regexp.MustCompile(`(?i)("[\w-]*(?:token|password|passwd|secret)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	fieldRe  = regexp.MustCompile(`(?i)\b(\w*(?:token|password|passwd|secret))(:|=)([^\s,}"]+)`)
+)
+
+// RedactString masks anything that looks like a secret in s: bearer and basic
+// credentials, sensitive JSON fields and sensitive struct fields formatted
+// with %+v
+func RedactString(s string) string {
+	s = bearerRe.ReplaceAllString(s, "$1 "+Redacted)
+	s = jsonRe.ReplaceAllString(s, `$1"`+Redacted+`"`)
+	s = fieldRe.ReplaceAllString(s, "${1}${2}"+Redacted)
+	return s
+}
+
+// RedactJSON masks the values of sensitive fields anywhere in a JSON
+// document. Anything that isn't valid JSON is redacted as a string.
+func RedactJSON(b []byte) []byte {
+	var doc interface{}
+	if e := json.Unmarshal(b, &doc); e != nil {
+		return []byte(RedactString(string(b)))
+	}
+	redacted, e := json.Marshal(redactValue(doc))
+	if e != nil {
+		return []byte(RedactString(string(b)))
+	}
+	return redacted
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if _, isString := val.(string); isString && sensitiveKey.MatchString(k) {
+				t[k] = Redacted
+				continue
+			}
+			t[k] = redactValue(val)
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = redactValue(t[i])
+		}
+	}
+	return v
+}
+
+// RedactHeader returns a copy of the header with the values of sensitive
+// headers masked. The authorization scheme is kept so that it's still clear
+// how the request was authenticated.
+func RedactHeader(h http.Header) http.Header {
+	redacted := h.Clone()
+	for _, name := range sensitiveHeaders {
+		values := redacted.Values(name)
+		for i, v := range values {
+			if bits := strings.SplitN(v, " ", 2); len(bits) == 2 && name != "Cookie" && name != "Set-Cookie" {
+				values[i] = bits[0] + " " + Redacted
+			} else {
+				values[i] = Redacted
+			}
+		}
+	}
+	return redacted
+}
+
+// dumpRequest formats an outgoing request like httputil.DumpRequestOut but
+// with the headers and body redacted
+func dumpRequest(r *http.Request) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s %s %s\r\n", r.Method, r.URL.RequestURI(), r.Proto)
+	fmt.Fprintf(b, "Host: %s\r\n", r.URL.Host)
+	_ = RedactHeader(r.Header).Write(b)
+	b.WriteString("\r\n")
+
+	if r.GetBody != nil {
+		if body, e := r.GetBody(); e == nil {
+			raw, _ := ioutil.ReadAll(body)
+			body.Close()
+			if len(bytes.TrimSpace(raw)) > 0 {
+				b.Write(RedactJSON(raw))
+			}
+		}
+	}
+	return b.String()
+}
+
+// dumpResponse formats the status line and headers of a response with the
+// headers redacted
+func dumpResponse(r *http.Response) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s %s\r\n", r.Proto, r.Status)
+	_ = RedactHeader(r.Header).Write(b)
+	return b.String()
+}
+
+// redact returns a version of a log message that is safe to print
+func redact(m interface{}) interface{} {
+	switch t := m.(type) {
+	case Redactor:
+		return t.Redacted()
+	case string:
+		return RedactString(t)
+	case error:
+		return RedactString(t.Error())
+	case fmt.Stringer:
+		return RedactString(t.String())
+	}
+	return m
+}
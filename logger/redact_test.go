@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactString(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"Authorization: Bearer abc.def", "Authorization: Bearer REDACTED"},
+		{`{"email":"a@b","password":"hunter2"}`, `{"email":"a@b","password":"REDACTED"}`},
+		{`{"jwt_token": "abc"}`, `{"jwt_token": "REDACTED"}`},
+		{"{JwtToken:abc.def}", "{JwtToken:REDACTED}"},
+		{"name=foo token=abc", "name=foo token=REDACTED"},
+		{"nothing to see", "nothing to see"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, RedactString(test.in))
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	in := `{"name":"t","token":"secret","nested":[{"password":"p","size":1}]}`
+	assert.JSONEq(
+		t,
+		`{"name":"t","token":"REDACTED","nested":[{"password":"REDACTED","size":1}]}`,
+		string(RedactJSON([]byte(in))),
+	)
+}
+
+func TestDumpRequest(t *testing.T) {
+	body := `{"email":"me@example.com","password":"hunter2"}`
+	req, e := http.NewRequest("POST", "https://conch.example.com/login", bytes.NewBufferString(body))
+	assert.Nil(t, e)
+	req.Header.Set("Authorization", "Bearer abc.def")
+
+	dump := dumpRequest(req)
+	assert.Contains(t, dump, "Authorization: Bearer REDACTED")
+	assert.Contains(t, dump, `"email":"me@example.com"`)
+	assert.NotContains(t, dump, "hunter2")
+	assert.NotContains(t, dump, "abc.def")
+
+	// the request itself is untouched
+	assert.Equal(t, "Bearer abc.def", req.Header.Get("Authorization"))
+}
+
+type secret struct{ Token string }
+
+func (s secret) Redacted() interface{} { return secret{Redacted} }
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, secret{Redacted}, redact(secret{"abc"}))
+	assert.True(t, strings.HasSuffix(redact("Bearer abc").(string), Redacted))
+}
@@ -15,6 +15,5 @@ var (
 )
 
 func main() {
-	app := cli.NewApp(cli.NewConfig(Version, GitRev))
-	app.Run(os.Args)
+	cli.Run(cli.NewConfig(Version, GitRev), os.Args)
 }
@@ -0,0 +1,359 @@
+/*
+Package spool keeps device reports that couldn't be sent in a directory on
+disk, to be sent again, in the order they were spooled, once the API is
+reachable.
+*/
+package spool
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joyent/kosh/conch"
+)
+
+// timeFormat is how times are written in the names of spooled reports. It
+// sorts in time order.
+const timeFormat = "20060102T150405.000000000Z"
+
+// rejectedDir is the subdirectory reports the API rejected are moved to
+const rejectedDir = "rejected"
+
+// Spool is a directory of device reports waiting to be sent
+type Spool struct {
+	Dir string
+	// Now returns the current time, used to stamp spooled reports
+	Now func() time.Time
+	// Sleep waits between attempts to send a report
+	Sleep func(time.Duration)
+}
+
+// New returns a Spool in the given directory, creating it if need be
+func New(dir string) (*Spool, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("no spool directory given")
+	}
+	if e := os.MkdirAll(dir, 0700); e != nil {
+		return nil, e
+	}
+	return &Spool{Dir: dir, Now: time.Now, Sleep: time.Sleep}, nil
+}
+
+// Entry is a single spooled report. Spooled is when it was spooled, which
+// orders the spool. Timestamp is the report's own timestamp, its
+// uptime_since, and is zero if it doesn't have one. Digest identifies the
+// content of the report.
+type Entry struct {
+	Path      string
+	Serial    string
+	Spooled   time.Time
+	Timestamp time.Time
+	Digest    string
+}
+
+// key identifies an entry for deduplication: reports with the same serial
+// number and timestamp are copies of the same report. Reports without a
+// timestamp are told apart by their content instead.
+func (e Entry) key() string {
+	if e.Timestamp.IsZero() {
+		return e.Serial + "@" + e.Digest
+	}
+	return e.Serial + "@" + e.Timestamp.Format(time.RFC3339Nano)
+}
+
+// noTimestamp stands in the file name for the timestamp of a report that
+// doesn't have one
+const noTimestamp = "-"
+
+// name is the file name of the entry, SPOOLED_TIMESTAMP_DIGEST_SERIAL.json
+func (e Entry) name() string {
+	timestamp := noTimestamp
+	if !e.Timestamp.IsZero() {
+		timestamp = e.Timestamp.UTC().Format(timeFormat)
+	}
+	return e.Spooled.Format(timeFormat) + "_" + timestamp + "_" + e.Digest + "_" + e.Serial + ".json"
+}
+
+// digestOf returns a short hash of a report, ignoring how its JSON is laid
+// out, or an error if it isn't a JSON object
+func digestOf(report []byte) (string, error) {
+	fields := map[string]json.RawMessage{}
+	if e := json.Unmarshal(report, &fields); e != nil {
+		return "", fmt.Errorf("not a valid device report: %s", e)
+	}
+	compact := &bytes.Buffer{}
+	if e := json.Compact(compact, report); e != nil {
+		return "", fmt.Errorf("not a valid device report: %s", e)
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// serialOf returns the serial number of a device report, made safe for a
+// file name, or "unknown" if it doesn't have one
+func serialOf(report []byte) string {
+	r := struct {
+		SerialNumber string `json:"serial_number"`
+	}{}
+	_ = json.Unmarshal(report, &r)
+	serial := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '_' || r <= ' ' {
+			return '-'
+		}
+		return r
+	}, r.SerialNumber)
+	if serial == "" {
+		return "unknown"
+	}
+	return serial
+}
+
+// timestampOf returns the timestamp of a device report, its uptime_since, or
+// the zero time if it doesn't have one
+func timestampOf(report []byte) time.Time {
+	r := struct {
+		UptimeSince string `json:"uptime_since"`
+	}{}
+	_ = json.Unmarshal(report, &r)
+	t, e := time.Parse(time.RFC3339Nano, r.UptimeSince)
+	if e != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+// Add writes a report to the spool and returns the entry for it. If a report
+// with the same serial number and timestamp is already waiting to be sent,
+// that entry is returned instead. The
+// file is written under a temporary name and renamed into place, so a partly
+// written report is never sent.
+func (s *Spool) Add(report []byte) (Entry, error) {
+	digest, e := digestOf(report)
+	if e != nil {
+		return Entry{}, e
+	}
+	entry := Entry{
+		Serial:    serialOf(report),
+		Spooled:   s.Now().UTC(),
+		Timestamp: timestampOf(report),
+		Digest:    digest,
+	}
+
+	entries, e := s.Entries()
+	if e != nil {
+		return entry, e
+	}
+	for _, pending := range entries {
+		if pending.key() == entry.key() {
+			return pending, nil
+		}
+	}
+
+	for {
+		entry.Path = filepath.Join(s.Dir, entry.name())
+		if _, e := os.Stat(entry.Path); os.IsNotExist(e) {
+			break
+		}
+		entry.Spooled = entry.Spooled.Add(time.Nanosecond)
+	}
+
+	tmp, e := ioutil.TempFile(s.Dir, ".spool-")
+	if e != nil {
+		return entry, e
+	}
+	defer os.Remove(tmp.Name())
+	if _, e := tmp.Write(report); e != nil {
+		tmp.Close()
+		return entry, e
+	}
+	if e := tmp.Sync(); e != nil {
+		tmp.Close()
+		return entry, e
+	}
+	if e := tmp.Close(); e != nil {
+		return entry, e
+	}
+	return entry, os.Rename(tmp.Name(), entry.Path)
+}
+
+// parseEntry parses the name of a spooled report file
+func parseEntry(dir, name string) (Entry, bool) {
+	if !strings.HasSuffix(name, ".json") {
+		return Entry{}, false
+	}
+	bits := strings.SplitN(strings.TrimSuffix(name, ".json"), "_", 4)
+	if len(bits) != 4 {
+		return Entry{}, false
+	}
+	spooled, e := time.Parse(timeFormat, bits[0])
+	if e != nil {
+		return Entry{}, false
+	}
+	entry := Entry{Path: filepath.Join(dir, name), Serial: bits[3], Spooled: spooled, Digest: bits[2]}
+	if bits[1] != noTimestamp {
+		if entry.Timestamp, e = time.Parse(timeFormat, bits[1]); e != nil {
+			return Entry{}, false
+		}
+	}
+	return entry, true
+}
+
+// Entries returns the spooled reports, oldest first
+func (s *Spool) Entries() ([]Entry, error) {
+	infos, e := ioutil.ReadDir(s.Dir)
+	if e != nil {
+		return nil, e
+	}
+	entries := []Entry{}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		if entry, ok := parseEntry(s.Dir, info.Name()); ok {
+			entries = append(entries, entry)
+		}
+	}
+	// the names start with the time they were spooled, so sort in order
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// Retryable reports whether sending a report could succeed if tried again:
+// the API couldn't be reached, or answered with a 5xx status or one for
+// authentication, timeouts or rate limiting. Anything else, such as the API
+// rejecting the report or the report not being readable, is final.
+func Retryable(e error) bool {
+	var httpErr *conch.HTTPError
+	if errors.As(e, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		}
+		return httpErr.StatusCode >= 500
+	}
+	// failures to connect, and connections dropped part way, come back
+	// from the HTTP client as a *url.Error, which is a net.Error
+	var netErr net.Error
+	return errors.As(e, &netErr)
+}
+
+// Outcomes of sending a spooled report
+const (
+	Delivered = "delivered"
+	Duplicate = "duplicate"
+	Rejected  = "rejected"
+	Pending   = "pending"
+)
+
+// Result is what happened to a spooled report when the spool was flushed
+type Result struct {
+	Entry
+	Status   string
+	Attempts int
+	Error    error
+}
+
+// Backoff is how often, and how long between, attempts to send each report
+type Backoff struct {
+	Attempts int
+	Initial  time.Duration
+	Max      time.Duration
+}
+
+// DefaultBackoff tries each report five times, waiting a second and then
+// twice as long each time, up to thirty seconds
+var DefaultBackoff = Backoff{Attempts: 5, Initial: time.Second, Max: 30 * time.Second}
+
+// send tries to send a single report, waiting between attempts
+func (s *Spool) send(report []byte, b Backoff, send func([]byte) error) (int, error) {
+	delay := b.Initial
+	var e error
+	for attempt := 1; ; attempt++ {
+		if e = send(report); e == nil || !Retryable(e) || attempt >= b.Attempts {
+			return attempt, e
+		}
+		s.Sleep(delay)
+		delay *= 2
+		if b.Max > 0 && delay > b.Max {
+			delay = b.Max
+		}
+	}
+}
+
+// Flush sends the spooled reports in the order they were spooled. Delivered
+// reports, and duplicates of one already sent with the same serial number and
+// timestamp, are removed. Reports the API rejects, or that aren't valid JSON,
+// are moved to the rejected subdirectory. If a report still can't be sent
+// after every attempt, flushing stops so that the order is kept, and it and
+// the reports after it are left pending.
+func (s *Spool) Flush(b Backoff, send func([]byte) error) ([]Result, error) {
+	entries, e := s.Entries()
+	if e != nil {
+		return nil, e
+	}
+
+	results := []Result{}
+	seen := map[string]bool{}
+	stopped := false
+	for _, entry := range entries {
+		result := Result{Entry: entry, Status: Pending}
+		switch {
+		case stopped:
+		case seen[entry.key()]:
+			result.Status = Duplicate
+			result.Error = os.Remove(entry.Path)
+		default:
+			seen[entry.key()] = true
+			report, e := ioutil.ReadFile(entry.Path)
+			if e != nil {
+				result.Error = e
+				stopped = true
+				break
+			}
+			if _, e := digestOf(report); e != nil {
+				result.Status = Rejected
+				result.Error = e
+				if e := s.reject(entry); e != nil {
+					result.Error = e
+				}
+				break
+			}
+			result.Attempts, result.Error = s.send(report, b, send)
+			switch {
+			case result.Error == nil:
+				result.Status = Delivered
+				result.Error = os.Remove(entry.Path)
+			case !Retryable(result.Error):
+				result.Status = Rejected
+				if e := s.reject(entry); e != nil {
+					result.Error = e
+				}
+			default:
+				stopped = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// reject moves a report the API won't accept out of the way
+func (s *Spool) reject(entry Entry) error {
+	dir := filepath.Join(s.Dir, rejectedDir)
+	if e := os.MkdirAll(dir, 0700); e != nil {
+		return e
+	}
+	return os.Rename(entry.Path, filepath.Join(dir, filepath.Base(entry.Path)))
+}
@@ -0,0 +1,172 @@
+package spool
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joyent/kosh/conch"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSpool(t *testing.T) (*Spool, *[]time.Duration) {
+	s, e := New(filepath.Join(t.TempDir(), "spool"))
+	assert.Nil(t, e)
+
+	now := time.Date(2020, 11, 2, 12, 0, 0, 0, time.UTC)
+	s.Now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	slept := []time.Duration{}
+	s.Sleep = func(d time.Duration) { slept = append(slept, d) }
+	return s, &slept
+}
+
+func TestAddAndEntries(t *testing.T) {
+	s, _ := testSpool(t)
+
+	first, e := s.Add([]byte(`{"serial_number": "S/1"}`))
+	assert.Nil(t, e)
+	assert.Equal(t, "S-1", first.Serial)
+	second, e := s.Add([]byte(`{}`))
+	assert.Nil(t, e)
+	assert.Equal(t, "unknown", second.Serial)
+
+	_, e = s.Add([]byte(`{"serial_number": "S2", `))
+	assert.NotNil(t, e, "a report that isn't valid JSON isn't spooled")
+
+	// files that aren't spooled reports are ignored
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(s.Dir, "notes.txt"), []byte("x"), 0600))
+
+	entries, e := s.Entries()
+	assert.Nil(t, e)
+	assert.Equal(t, []Entry{first, second}, entries)
+}
+
+func TestAddSameReportTwice(t *testing.T) {
+	s, _ := testSpool(t)
+
+	first, e := s.Add([]byte(`{"serial_number": "S1", "uptime_since": "2020-11-02T10:59:59Z", "temp": {"cpu0": 45}}`))
+	assert.Nil(t, e)
+	assert.Equal(t, time.Date(2020, 11, 2, 10, 59, 59, 0, time.UTC), first.Timestamp)
+	// a report with the same serial number and timestamp is the same report,
+	// whatever else has changed
+	again, e := s.Add([]byte(`{"serial_number":"S1","uptime_since":"2020-11-02T10:59:59Z","temp":{"cpu0":47}}`))
+	assert.Nil(t, e)
+	assert.Equal(t, first, again)
+	later, e := s.Add([]byte(`{"serial_number": "S1", "uptime_since": "2020-11-02T11:30:00Z"}`))
+	assert.Nil(t, e)
+
+	// without a timestamp, only a report with the same content is the same
+	untimed, e := s.Add([]byte(`{"serial_number": "S1", "temp": {"cpu0": 45}}`))
+	assert.Nil(t, e)
+	assert.True(t, untimed.Timestamp.IsZero())
+	again, e = s.Add([]byte(`{"serial_number":"S1","temp":{"cpu0":45}}`))
+	assert.Nil(t, e)
+	assert.Equal(t, untimed, again)
+	changed, e := s.Add([]byte(`{"serial_number": "S1", "temp": {"cpu0": 47}}`))
+	assert.Nil(t, e)
+
+	entries, e := s.Entries()
+	assert.Nil(t, e)
+	assert.Equal(t, []Entry{first, later, untimed, changed}, entries)
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, Retryable(&url.Error{Op: "Post", URL: "https://conch/device_report", Err: errors.New("connection refused")}))
+	assert.False(t, Retryable(errors.New("unexpected end of JSON input")))
+	assert.True(t, Retryable(&conch.HTTPError{StatusCode: http.StatusBadGateway}))
+	assert.True(t, Retryable(&conch.HTTPError{StatusCode: http.StatusUnauthorized}))
+	assert.True(t, Retryable(&conch.HTTPError{StatusCode: http.StatusTooManyRequests}))
+	assert.False(t, Retryable(&conch.HTTPError{StatusCode: http.StatusBadRequest}))
+	assert.False(t, Retryable(&conch.HTTPError{StatusCode: http.StatusConflict}))
+}
+
+func TestFlush(t *testing.T) {
+	s, slept := testSpool(t)
+
+	for _, serial := range []string{"S1", "BAD", "S2", "DOWN", "S3"} {
+		_, e := s.Add([]byte(`{"serial_number": "` + serial + `", "uptime_since": "2020-11-02T10:59:59Z"}`))
+		assert.Nil(t, e)
+	}
+	// a report re-sent with the same serial number and timestamp, as another
+	// host's spool might have, is a duplicate even if its temperatures moved
+	entries, e := s.Entries()
+	assert.Nil(t, e)
+	resent := entries[0]
+	resent.Spooled = resent.Spooled.Add(time.Millisecond)
+	resent.Digest = "0000000000000000"
+	report := []byte(`{"serial_number": "S1", "uptime_since": "2020-11-02T10:59:59Z", "temp": {"cpu0": 47}}`)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(s.Dir, resent.name()), report, 0600))
+
+	sent := []string{}
+	backoff := Backoff{Attempts: 3, Initial: time.Second, Max: 3 * time.Second}
+	results, e := s.Flush(backoff, func(report []byte) error {
+		serial := serialOf(report)
+		sent = append(sent, serial)
+		switch serial {
+		case "BAD":
+			return &conch.HTTPError{StatusCode: http.StatusBadRequest}
+		case "DOWN":
+			return &conch.HTTPError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+	assert.Nil(t, e)
+
+	statuses := []string{}
+	for _, r := range results {
+		statuses = append(statuses, r.Serial+" "+r.Status)
+	}
+	assert.Equal(t, []string{
+		"S1 delivered",
+		"S1 duplicate",
+		"BAD rejected",
+		"S2 delivered",
+		"DOWN pending",
+		"S3 pending",
+	}, statuses)
+	assert.Equal(t, []string{"S1", "BAD", "S2", "DOWN", "DOWN", "DOWN"}, sent)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *slept)
+	assert.Equal(t, 3, results[4].Attempts)
+
+	entries, e = s.Entries()
+	assert.Nil(t, e)
+	assert.Len(t, entries, 2)
+	rejected, e := filepath.Glob(filepath.Join(s.Dir, rejectedDir, "*_BAD.json"))
+	assert.Nil(t, e)
+	assert.Len(t, rejected, 1)
+}
+
+func TestFlushMalformedEntry(t *testing.T) {
+	s, _ := testSpool(t)
+
+	broken := Entry{Serial: "S1", Spooled: s.Now(), Digest: "0000000000000000"}
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(s.Dir, broken.name()), []byte(`{"serial_number": "S1", `), 0600))
+	_, e := s.Add([]byte(`{"serial_number": "S2"}`))
+	assert.Nil(t, e)
+
+	sent := []string{}
+	results, e := s.Flush(DefaultBackoff, func(report []byte) error {
+		sent = append(sent, serialOf(report))
+		return nil
+	})
+	assert.Nil(t, e)
+	assert.Len(t, results, 2)
+	assert.Equal(t, Rejected, results[0].Status)
+	assert.NotNil(t, results[0].Error)
+	assert.Equal(t, Delivered, results[1].Status, "a malformed report doesn't hold up the rest")
+	assert.Equal(t, []string{"S2"}, sent)
+
+	entries, e := s.Entries()
+	assert.Nil(t, e)
+	assert.Empty(t, entries)
+	rejected, e := filepath.Glob(filepath.Join(s.Dir, rejectedDir, "*_S1.json"))
+	assert.Nil(t, e)
+	assert.Len(t, rejected, 1)
+}
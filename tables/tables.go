@@ -4,10 +4,13 @@ Package tables renders tabular data.
 package tables
 
 import (
+	"fmt"
 	"io"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/olekukonko/tablewriter"
 )
 
@@ -33,17 +36,294 @@ type Tabulable interface {
 	sort.Interface
 }
 
+// Options controls which columns of a Tabulable are rendered and in what
+// order the rows appear. The zero value renders every column, sorted by the
+// type's own Less method.
+type Options struct {
+	// Columns is the list of header names to display, in order. Matching is
+	// case insensitive.
+	Columns []string
+
+	// SortBy is the header name of the column to sort rows by. When empty
+	// the Tabulable's own sort order is used.
+	SortBy string
+
+	// Descending reverses the sort order given by SortBy
+	Descending bool
+
+	// NoHeaders omits the header row
+	NoHeaders bool
+
+	// MaxWidth is the widest the rendered table may be, usually the width of
+	// the terminal. The widest columns are truncated until the table fits.
+	// Zero means no limit.
+	MaxWidth int
+
+	// Color, if set, is applied to every cell after any truncation. It is
+	// given the header of the cell's column and returns the cell to render.
+	Color func(header, cell string) string
+}
+
+// ParseColumns takes a comma separated list of column names and returns them
+// as a slice, dropping any empty entries
+func ParseColumns(s string) []string {
+	columns := []string{}
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// ParseSortBy takes a string of the form "column" or "column:desc" (or
+// "column:asc") and returns the column name and whether the sort should be
+// descending
+func ParseSortBy(s string) (column string, descending bool, e error) {
+	bits := strings.SplitN(s, ":", 2)
+	column = strings.TrimSpace(bits[0])
+	if len(bits) == 1 {
+		return
+	}
+
+	switch strings.ToLower(strings.TrimSpace(bits[1])) {
+	case "desc":
+		descending = true
+	case "asc", "":
+	default:
+		e = fmt.Errorf("sort order must be one of asc, desc: got '%s'", bits[1])
+	}
+	return
+}
+
+func columnIndex(headers []string, name string) (int, error) {
+	for i, h := range headers {
+		if strings.EqualFold(h, name) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf(
+		"unknown column '%s': must be one of %s",
+		name,
+		strings.Join(headers, ", "),
+	)
+}
+
+// lessCell compares two cells numerically if they both parse as numbers and
+// lexically otherwise
+func lessCell(a, b string) bool {
+	x, ex := strconv.ParseFloat(a, 64)
+	y, ey := strconv.ParseFloat(b, 64)
+	if ex == nil && ey == nil {
+		return x < y
+	}
+	return a < b
+}
+
+// Identified is implemented by Tabulable types whose identity column isn't
+// "ID" or the first column
+type Identified interface {
+	IdentityColumn() string
+}
+
+// IdentityColumn returns the index of the column that uniquely identifies a
+// row in the given Tabulable. This is the column named by IdentityColumn() if
+// the type implements Identified, the "ID" column if there is one, and
+// otherwise the first column.
+func IdentityColumn(list Tabulable) int {
+	headers := list.Headers()
+	if t, ok := list.(Identified); ok {
+		if i, e := columnIndex(headers, t.IdentityColumn()); e == nil {
+			return i
+		}
+	}
+	if i, e := columnIndex(headers, "ID"); e == nil {
+		return i
+	}
+	return 0
+}
+
 // Render takess a Tabulable struct and renders it into markdown compatible
 // string
 func Render(list Tabulable) string {
+	s, _ := RenderWith(list, Options{})
+	return s
+}
+
+// RenderWith takes a Tabulable struct and renders it into a markdown
+// compatible string, applying the column selection and sorting given in opts.
+// It returns an error if opts refers to a column the Tabulable doesn't have.
+func RenderWith(list Tabulable, opts Options) (string, error) {
+	headers, rows, e := Rows(list, opts)
+	if e != nil {
+		return "", e
+	}
+
+	headers, rows, e = SelectColumns(headers, rows, opts.Columns)
+	if e != nil {
+		return "", e
+	}
+
+	return RenderRows(headers, rows, opts), nil
+}
+
+// Rows returns the headers and every row of the given Tabulable, sorted
+// according to opts. Column selection is not applied.
+func Rows(list Tabulable, opts Options) (headers []string, rows [][]string, e error) {
 	sort.Sort(list)
 
+	headers = list.Headers()
+
+	rows = [][]string{}
+	list.ForEach(func(row []string) { rows = append(rows, row) })
+
+	if opts.SortBy != "" {
+		i, e := columnIndex(headers, opts.SortBy)
+		if e != nil {
+			return nil, nil, e
+		}
+		sort.SliceStable(rows, func(a, b int) bool {
+			if opts.Descending {
+				return lessCell(rows[b][i], rows[a][i])
+			}
+			return lessCell(rows[a][i], rows[b][i])
+		})
+	}
+	return
+}
+
+// SelectColumns reduces the headers and rows down to the named columns, in
+// the given order. If columns is empty the headers and rows are returned
+// unchanged.
+func SelectColumns(headers []string, rows [][]string, columns []string) ([]string, [][]string, error) {
+	if len(columns) == 0 {
+		return headers, rows, nil
+	}
+
+	indexes := make([]int, len(columns))
+	for n, c := range columns {
+		i, e := columnIndex(headers, c)
+		if e != nil {
+			return nil, nil, e
+		}
+		indexes[n] = i
+	}
+
+	picked := make([][]string, len(rows))
+	for n, row := range rows {
+		picked[n] = pick(row, indexes)
+	}
+	return pick(headers, indexes), picked, nil
+}
+
+// RenderRows renders the given headers and rows into a markdown compatible
+// string. Only the NoHeaders, MaxWidth and Color fields of opts are used.
+func RenderRows(headers []string, rows [][]string, opts Options) string {
 	tableString := &strings.Builder{}
 	table := NewTable(tableString)
 
-	table.SetHeader(list.Headers())
-	list.ForEach(table.Append)
+	names := headers
+	if opts.MaxWidth > 0 {
+		headers, rows = fit(headers, rows, opts.MaxWidth, opts.NoHeaders)
+	}
+
+	if opts.Color != nil {
+		colored := make([][]string, len(rows))
+		for i, row := range rows {
+			colored[i] = make([]string, len(row))
+			for j, cell := range row {
+				if j < len(names) {
+					cell = opts.Color(names[j], cell)
+				}
+				colored[i][j] = cell
+			}
+		}
+		rows = colored
+	}
+
+	if !opts.NoHeaders {
+		table.SetHeader(headers)
+	}
+	table.AppendBulk(rows)
 
 	table.Render()
 	return tableString.String()
 }
+
+// minColumnWidth is the narrowest fit will truncate a column to
+const minColumnWidth = 5
+
+// fit truncates the widest columns, one character at a time, until the table
+// would be no wider than maxWidth. Columns aren't truncated below
+// minColumnWidth so very narrow terminals may still overflow.
+func fit(headers []string, rows [][]string, maxWidth int, noHeaders bool) ([]string, [][]string) {
+	widths := make([]int, len(headers))
+	if !noHeaders {
+		for i, h := range headers {
+			widths[i] = runewidth.StringWidth(h)
+		}
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i < len(widths) && runewidth.StringWidth(cell) > widths[i] {
+				widths[i] = runewidth.StringWidth(cell)
+			}
+		}
+	}
+
+	// each column is padded with a space either side and followed by a
+	// separator, plus the separator at the start of the row
+	total := 1
+	for _, w := range widths {
+		total += w + 3
+	}
+
+	truncated := false
+	for total > maxWidth {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if len(widths) == 0 || widths[widest] <= minColumnWidth {
+			break
+		}
+		widths[widest]--
+		total--
+		truncated = true
+	}
+	if !truncated {
+		return headers, rows
+	}
+
+	truncate := func(s string, i int) string {
+		if i >= len(widths) {
+			return s
+		}
+		return runewidth.Truncate(s, widths[i], "…")
+	}
+
+	fitHeaders := make([]string, len(headers))
+	for i, h := range headers {
+		fitHeaders[i] = truncate(h, i)
+	}
+	fitRows := make([][]string, len(rows))
+	for r, row := range rows {
+		fitRows[r] = make([]string, len(row))
+		for i, cell := range row {
+			fitRows[r][i] = truncate(cell, i)
+		}
+	}
+	return fitHeaders, fitRows
+}
+
+func pick(row []string, indexes []int) []string {
+	picked := make([]string, len(indexes))
+	for n, i := range indexes {
+		if i < len(row) {
+			picked[n] = row[i]
+		}
+	}
+	return picked
+}
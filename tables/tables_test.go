@@ -0,0 +1,137 @@
+package tables_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joyent/kosh/tables"
+	"github.com/mattn/go-runewidth"
+	"github.com/stretchr/testify/assert"
+)
+
+type fruits [][]string
+
+func (f fruits) Len() int           { return len(f) }
+func (f fruits) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+func (f fruits) Less(i, j int) bool { return f[i][0] < f[j][0] }
+
+func (f fruits) Headers() []string { return []string{"Name", "Color", "Count"} }
+
+func (f fruits) ForEach(do func([]string)) {
+	for _, row := range f {
+		do(row)
+	}
+}
+
+func newFruits() fruits {
+	return fruits{
+		{"cherry", "red", "10"},
+		{"apple", "green", "9"},
+		{"banana", "yellow", "100"},
+	}
+}
+
+func firstColumn(s string) []string {
+	column := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		cells := strings.Split(line, "|")
+		if len(cells) > 1 && !strings.HasPrefix(strings.TrimSpace(cells[1]), "-") {
+			column = append(column, strings.TrimSpace(cells[1]))
+		}
+	}
+	return column
+}
+
+func TestRenderWith(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Options  tables.Options
+		Expected []string
+	}{
+		{
+			Name:     "default sort",
+			Options:  tables.Options{},
+			Expected: []string{"NAME", "apple", "banana", "cherry"},
+		},
+		{
+			Name:     "sort by count is numeric",
+			Options:  tables.Options{SortBy: "count"},
+			Expected: []string{"NAME", "apple", "cherry", "banana"},
+		},
+		{
+			Name:     "sort descending",
+			Options:  tables.Options{SortBy: "Color", Descending: true},
+			Expected: []string{"NAME", "banana", "cherry", "apple"},
+		},
+		{
+			Name:     "column selection",
+			Options:  tables.Options{Columns: []string{"color", "name"}},
+			Expected: []string{"COLOR", "green", "yellow", "red"},
+		},
+		{
+			Name:     "no headers",
+			Options:  tables.Options{NoHeaders: true},
+			Expected: []string{"apple", "banana", "cherry"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			s, e := tables.RenderWith(newFruits(), test.Options)
+			assert.Nil(t, e)
+			assert.Equal(t, test.Expected, firstColumn(s))
+		})
+	}
+}
+
+func TestRenderWithUnknownColumn(t *testing.T) {
+	_, e := tables.RenderWith(newFruits(), tables.Options{Columns: []string{"weight"}})
+	assert.NotNil(t, e)
+
+	_, e = tables.RenderWith(newFruits(), tables.Options{SortBy: "weight"})
+	assert.NotNil(t, e)
+}
+
+func TestParseSortBy(t *testing.T) {
+	column, desc, e := tables.ParseSortBy("Name:desc")
+	assert.Nil(t, e)
+	assert.Equal(t, "Name", column)
+	assert.True(t, desc)
+
+	column, desc, e = tables.ParseSortBy("Name")
+	assert.Nil(t, e)
+	assert.Equal(t, "Name", column)
+	assert.False(t, desc)
+
+	_, _, e = tables.ParseSortBy("Name:sideways")
+	assert.NotNil(t, e)
+}
+
+func TestRenderRowsMaxWidth(t *testing.T) {
+	headers := []string{"Name", "Description"}
+	rows := [][]string{{"apple", "a crisp and juicy fruit from a tree"}}
+
+	s := tables.RenderRows(headers, rows, tables.Options{MaxWidth: 30})
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		assert.True(t, runewidth.StringWidth(line) <= 30, line)
+	}
+	assert.Contains(t, s, "a crisp and juicy…")
+
+	s = tables.RenderRows(headers, rows, tables.Options{MaxWidth: 80})
+	assert.Contains(t, s, "a crisp and juicy fruit from a tree")
+}
+
+func TestRenderRowsColor(t *testing.T) {
+	s := tables.RenderRows(
+		[]string{"Name", "Status"},
+		[][]string{{"apple", "ok"}},
+		tables.Options{Color: func(header, cell string) string {
+			if header == "Status" {
+				return "<" + cell + ">"
+			}
+			return cell
+		}},
+	)
+	assert.Contains(t, s, "<ok>")
+	assert.NotContains(t, s, "<apple>")
+}
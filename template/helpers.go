@@ -0,0 +1,224 @@
+package template
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NoColor disables the colour helpers, which then return their input
+// unchanged
+var NoColor bool
+
+var colors = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+	"bold":    "1",
+	"dim":     "2",
+}
+
+// Color wraps a value in the ANSI escape sequence for the named colour:
+// black, red, green, yellow, blue, magenta, cyan, white, bold or dim.
+// Unknown colours are ignored.
+func Color(name string, v interface{}) string {
+	s := fmt.Sprint(v)
+	code, ok := colors[strings.ToLower(name)]
+	if NoColor || !ok || s == "" {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
+
+// statusColors maps the device health and validation status values onto
+// the colour they are shown in
+var statusColors = map[string]string{
+	"pass":    "green",
+	"ok":      "green",
+	"fail":    "red",
+	"error":   "red",
+	"unknown": "yellow",
+	"warn":    "yellow",
+}
+
+// Status colours a DeviceHealth or ValidationStatus value: green for pass,
+// red for fail and error, yellow for unknown and warn
+func Status(v interface{}) string {
+	s := fmt.Sprint(v)
+	if c, ok := statusColors[strings.ToLower(s)]; ok {
+		return Color(c, s)
+	}
+	return s
+}
+
+// toFloat converts the numbers found in decoded JSON, including numeric
+// strings, into a float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case nil:
+		return 0, false
+	case string:
+		f, e := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, e == nil
+	case fmt.Stringer:
+		f, e := strconv.ParseFloat(n.String(), 64)
+		return f, e == nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// Bytes renders a number of bytes in the largest binary unit that keeps it
+// at or above one, for example "1.5 GiB". Values that aren't numbers are
+// returned as they are.
+func Bytes(v interface{}) string {
+	f, ok := toFloat(v)
+	if !ok {
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+
+	unit := 0
+	for math.Abs(f) >= 1024 && unit < len(byteUnits)-1 {
+		f /= 1024
+		unit++
+	}
+	if unit == 0 || f == math.Trunc(f) {
+		return fmt.Sprintf("%.0f %s", f, byteUnits[unit])
+	}
+	return fmt.Sprintf("%.1f %s", f, byteUnits[unit])
+}
+
+// MegaBytes is Bytes for a value in megabytes, which is how device reports
+// record disk sizes (types.DiskSizeUnit)
+func MegaBytes(v interface{}) string {
+	f, ok := toFloat(v)
+	if !ok {
+		return Bytes(v)
+	}
+	return Bytes(f * 1024 * 1024)
+}
+
+// now is replaced in tests
+var now = time.Now
+
+// Ago describes a time relative to now, for example "3h ago" or "in 2d".
+// The zero time is rendered as an empty string.
+func Ago(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	d := now().Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var s string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		s = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		s = fmt.Sprintf("%dh", int(d/time.Hour))
+	case d < 365*24*time.Hour:
+		s = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	default:
+		s = fmt.Sprintf("%dy", int(d/(365*24*time.Hour)))
+	}
+
+	if future {
+		return "in " + s
+	}
+	return s + " ago"
+}
+
+// Join joins the elements of any slice with the separator
+func Join(sep string, v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+
+	s := make([]string, rv.Len())
+	for i := range s {
+		s[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(s, sep)
+}
+
+// Default returns the value unless it is empty (nil, zero or of zero length)
+// in which case it returns def. It's written for pipelines:
+// {{ .Hostname | Default "-" }}
+func Default(def, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		if rv.Len() == 0 {
+			return def
+		}
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return def
+		}
+	default:
+		if t, ok := v.(time.Time); ok && t.IsZero() {
+			return def
+		}
+		if rv.IsZero() {
+			return def
+		}
+	}
+	return v
+}
+
+// Pad pads a value with spaces on the right to the given width. A negative
+// width pads on the left instead.
+func Pad(width int, v interface{}) string {
+	if width < 0 {
+		return fmt.Sprintf("%*s", -width, fmt.Sprint(v))
+	}
+	return fmt.Sprintf("%-*s", width, fmt.Sprint(v))
+}
+
+// Indent indents every non-empty line of a value by the given number of
+// spaces
+func Indent(spaces int, v interface{}) string {
+	prefix := strings.Repeat(" ", spaces)
+	lines := strings.Split(fmt.Sprint(v), "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -6,8 +6,13 @@ package template
 
 import (
 	"bytes"
-	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"strconv"
+	"text/template"
 	"time"
 
 	"github.com/joyent/kosh/tables"
@@ -23,8 +28,15 @@ func YesOrNo(p bool) string {
 	return "No"
 }
 
+// FullUUIDs disables the shortening done by CutUUID, this is used for "wide"
+// output where the complete identifier is wanted
+var FullUUIDs bool
+
 // CutUUID - trims a UUID down to a short readable version
 func CutUUID(id string) string {
+	if FullUUIDs {
+		return id
+	}
 	re := regexp.MustCompile("^(.+?)-")
 	bits := re.FindStringSubmatch(id)
 	if len(bits) > 0 {
@@ -33,12 +45,51 @@ func CutUUID(id string) string {
 	return id
 }
 
-// TimeStr formats a time value into something human readable
+// The formats TimeStr can render times in
+const (
+	// TimeFormatLocal is a human readable date and time, the default
+	TimeFormatLocal = "local"
+	// TimeFormatRFC3339 is the machine readable RFC 3339 format
+	TimeFormatRFC3339 = "rfc3339"
+	// TimeFormatRelative is relative to now, for example "3h ago"
+	TimeFormatRelative = "relative"
+	// TimeFormatUnix is the number of seconds since the Unix epoch
+	TimeFormatUnix = "unix"
+)
+
+// TimeFormat is the format TimeStr renders times in, one of the TimeFormat
+// constants
+var TimeFormat = TimeFormatLocal
+
+// Location is the time zone TimeStr renders times in
+var Location = time.Local
+
+// ValidTimeFormat reports whether the given string is one of the TimeFormat
+// constants
+func ValidTimeFormat(format string) bool {
+	switch format {
+	case TimeFormatLocal, TimeFormatRFC3339, TimeFormatRelative, TimeFormatUnix:
+		return true
+	}
+	return false
+}
+
+// TimeStr formats a time value according to TimeFormat and Location
 func TimeStr(t time.Time) string {
 	if t.IsZero() {
 		return ""
 	}
-	return t.Local().Format(dateFormat)
+
+	t = t.In(Location)
+	switch TimeFormat {
+	case TimeFormatRFC3339:
+		return t.Format(time.RFC3339)
+	case TimeFormatRelative:
+		return Ago(t)
+	case TimeFormatUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.Format(dateFormat)
 }
 
 // Table formats Tabulable data using the tables package
@@ -46,13 +97,26 @@ func Table(t tables.Tabulable) string {
 	return tables.Render(t)
 }
 
-// NewTemplate returns a new template instance
+// Funcs are the helper functions available to every template
+var Funcs = map[string]interface{}{
+	"CutUUID":   CutUUID,
+	"TimeStr":   func(t time.Time) string { return TimeStr(t) },
+	"Table":     Table,
+	"Bytes":     Bytes,
+	"MegaBytes": MegaBytes,
+	"Ago":       Ago,
+	"Join":      Join,
+	"Default":   Default,
+	"Pad":       Pad,
+	"Indent":    Indent,
+	"Color":     Color,
+	"Status":    Status,
+}
+
+// NewTemplate returns a new template instance. Output is plain text, nothing
+// is escaped.
 func NewTemplate() *template.Template {
-	return template.New("wat").Funcs(template.FuncMap{
-		"CutUUID": CutUUID, // func(id string) string { return CutUUID(id) },
-		"TimeStr": func(t time.Time) string { return TimeStr(t) },
-		"Table":   Table,
-	})
+	return template.New("wat").Funcs(Funcs)
 }
 
 // Templated tracks what the template is for a given data structure
@@ -60,10 +124,61 @@ type Templated interface {
 	Template() string
 }
 
+// Dir is the directory searched for user templates that override the built in
+// ones. A user template is named after the type it renders, for example
+// DetailedDevice.tmpl. If Dir is empty only the built in templates are used.
+var Dir string
+
+// Name returns the name of the type of a Templated value, which is also the
+// base name of its user template file
+func Name(data Templated) string {
+	t := reflect.TypeOf(data)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// Path returns the path the user template for the given value would have.
+// It is empty if Dir is not set.
+func Path(data Templated) string {
+	if Dir == "" {
+		return ""
+	}
+	return filepath.Join(Dir, Name(data)+".tmpl")
+}
+
+// Lookup returns the template for the given value, preferring a user template
+// in Dir over the built in one. The path of the user template is returned if
+// one was used.
+func Lookup(data Templated) (text string, path string, err error) {
+	path = Path(data)
+	if path == "" {
+		return data.Template(), "", nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return data.Template(), "", nil
+	}
+	if err != nil {
+		return "", path, err
+	}
+	return string(b), path, nil
+}
+
 // Render takes a Templated datapiecea and returns a markdown compatible string
 func Render(data Templated) (string, error) {
-	template := data.Template()
-	t, err := NewTemplate().Parse(template)
+	template, path, err := Lookup(data)
+	if err != nil {
+		return "", err
+	}
+	name := "built in " + Name(data) + " template"
+	if path != "" {
+		name = path
+	}
+
+	t, err := NewTemplate().New(name).Parse(template)
 	if err != nil {
 		return "", err // TODO get logging in here
 	}
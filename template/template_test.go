@@ -0,0 +1,113 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type card struct{ Name string }
+
+func (c card) Template() string { return "built in {{ .Name }}" }
+
+func TestRenderUserTemplate(t *testing.T) {
+	dir, e := ioutil.TempDir("", "kosh-templates")
+	assert.Nil(t, e)
+	defer os.RemoveAll(dir)
+
+	defer func(d string) { Dir = d }(Dir)
+	Dir = dir
+
+	s, e := Render(card{"foo"})
+	assert.Nil(t, e)
+	assert.Equal(t, "built in foo", s)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "card.tmpl"), []byte("user {{ .Name }}"), 0600))
+
+	s, e = Render(&card{"foo"})
+	assert.Nil(t, e)
+	assert.Equal(t, "user foo", s)
+}
+
+func TestRenderDoesNotEscape(t *testing.T) {
+	s, e := Render(card{`<rack & "room">`})
+	assert.Nil(t, e)
+	assert.Equal(t, `built in <rack & "room">`, s)
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		in       interface{}
+		expected string
+	}{
+		{512, "512 B"},
+		{1536, "1.5 KiB"},
+		{float64(1 << 30), "1 GiB"},
+		{"2048", "2 KiB"},
+		{nil, ""},
+		{"unknown", "unknown"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Bytes(test.in), test.in)
+	}
+	assert.Equal(t, "3.6 TiB", MegaBytes(3815447))
+}
+
+func TestAgo(t *testing.T) {
+	fixed := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return fixed }
+
+	assert.Equal(t, "", Ago(time.Time{}))
+	assert.Equal(t, "just now", Ago(fixed.Add(-10*time.Second)))
+	assert.Equal(t, "3h ago", Ago(fixed.Add(-3*time.Hour)))
+	assert.Equal(t, "2d ago", Ago(fixed.Add(-49*time.Hour)))
+	assert.Equal(t, "in 5m", Ago(fixed.Add(5*time.Minute)))
+}
+
+func TestHelpers(t *testing.T) {
+	assert.Equal(t, "a, b", Join(", ", []string{"a", "b"}))
+	assert.Equal(t, "-", Default("-", ""))
+	assert.Equal(t, "x", Default("-", "x"))
+	assert.Equal(t, "-", Default("-", 0))
+	assert.Equal(t, "ab  |", Pad(4, "ab")+"|")
+	assert.Equal(t, "  ab", Pad(-4, "ab"))
+	assert.Equal(t, "  a\n\n  b", Indent(2, "a\n\nb"))
+
+	defer func(b bool) { NoColor = b }(NoColor)
+	NoColor = false
+	assert.Equal(t, "\033[31mbad\033[0m", Color("red", "bad"))
+	NoColor = true
+	assert.Equal(t, "bad", Color("red", "bad"))
+}
+
+func TestTimeStr(t *testing.T) {
+	defer func(f string, l *time.Location) { TimeFormat, Location = f, l }(TimeFormat, Location)
+
+	la, e := time.LoadLocation("America/Los_Angeles")
+	assert.Nil(t, e)
+	at := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	Location = time.UTC
+	assert.Equal(t, "2020-01-02 12:00:00 +0000 UTC", TimeStr(at))
+	assert.Equal(t, "", TimeStr(time.Time{}))
+
+	Location = la
+	TimeFormat = TimeFormatRFC3339
+	assert.Equal(t, "2020-01-02T04:00:00-08:00", TimeStr(at))
+
+	TimeFormat = TimeFormatUnix
+	assert.Equal(t, "1577966400", TimeStr(at))
+
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return at.Add(3 * time.Hour) }
+	TimeFormat = TimeFormatRelative
+	assert.Equal(t, "3h ago", TimeStr(at))
+
+	assert.True(t, ValidTimeFormat("unix"))
+	assert.False(t, ValidTimeFormat("iso"))
+}